@@ -0,0 +1,144 @@
+// Package envx loads per-environment settings, such as the registry, kube
+// context, namespace and chart values of dev, staging or prod, from .env
+// files and named profiles, so they no longer have to be sourced by hand.
+// A profile is applied to the commands of a runner with its WithProfile option.
+package envx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// ProfileEnv is the environment variable naming the profile LoadDefault loads,
+// e.g. MAGE_PROFILE=staging
+const ProfileEnv = "MAGE_PROFILE"
+
+// Profile is the environment resolved from the .env files of a directory
+type Profile struct {
+	Name  string   // Profile name; empty for the .env files alone
+	Files []string // Files that were loaded, lowest precedence first
+	vars  map[string]string
+}
+
+// Files returns the files Load reads for a profile, lowest precedence first:
+// .env, .env.local, .env.<name> and .env.<name>.local. The .local files are
+// meant for personal overrides that are not committed.
+func Files(dir, name string) []string {
+	files := []string{".env", ".env.local"}
+	if name != "" {
+		files = append(files, ".env."+name, ".env."+name+".local")
+	}
+	for i, file := range files {
+		files[i] = filepath.Join(dir, file)
+	}
+	return files
+}
+
+// Load resolves the profile name from the files in dir. A later file overrides
+// the variables of an earlier one, and variables already set in our own
+// environment override them all, so `FOO=bar mage deploy` still works.
+// ${VAR} references see the variables resolved so far. Missing files are
+// skipped, but a named profile needs at least one file of its own.
+func Load(dir, name string) (*Profile, error) {
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	p := &Profile{Name: name, vars: make(map[string]string)}
+	for _, file := range Files(dir, name) {
+		f, err := os.Open(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load profile: %w", err)
+		}
+		// References see our environment first, like Lookup
+		err = parse(f, p.Lookup, func(v Var) { p.vars[v.Name] = v.Value })
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", file, err)
+		}
+		p.Files = append(p.Files, file)
+	}
+	if name != "" && !slices.ContainsFunc(p.Files, func(file string) bool {
+		return strings.HasPrefix(filepath.Base(file), ".env."+name)
+	}) {
+		return nil, fmt.Errorf("profile %q not found: no .env.%s or .env.%s.local in %s", name, name, name, dir)
+	}
+	if len(p.Files) > 0 {
+		logx.Default().Info("📄 Loaded environment profile", "profile", name, "files", p.Files)
+	}
+	return p, nil
+}
+
+// LoadDefault loads the profile named by ProfileEnv from the current directory
+func LoadDefault() (*Profile, error) {
+	return Load(".", os.Getenv(ProfileEnv))
+}
+
+// Lookup returns the value of a variable: ours if it is set in our
+// environment, otherwise the one from the profile's files
+func (p *Profile) Lookup(name string) (string, bool) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true
+	}
+	if p == nil {
+		return "", false
+	}
+	v, ok := p.vars[name]
+	return v, ok
+}
+
+// Get returns the value of a variable, or an empty string if it is not set
+func (p *Profile) Get(name string) string {
+	v, _ := p.Lookup(name)
+	return v
+}
+
+// List splits the value of a variable at commas, e.g. HELM_VALUES=base.yaml,prod.yaml
+func (p *Profile) List(name string) []string {
+	var list []string
+	for _, item := range strings.Split(p.Get(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Environ returns the variables defined by the profile's files as sorted
+// KEY=VALUE pairs, with the values they resolve to
+func (p *Profile) Environ() []string {
+	if p == nil {
+		return nil
+	}
+	env := make([]string, 0, len(p.vars))
+	for _, name := range slices.Sorted(maps.Keys(p.vars)) {
+		env = append(env, name+"="+p.Get(name))
+	}
+	return env
+}
+
+// Option makes commands inherit the profile's variables as execx.BaseEnv, so
+// they are not shown in logged command lines, yet still reach commands that
+// run hermetic. Variables set with execx.Env override them.
+func (p *Profile) Option() execx.Option {
+	return execx.BaseEnv(p.Environ()...)
+}
+
+// Context returns a copy of ctx whose commands get the profile's variables
+func (p *Profile) Context(ctx context.Context) context.Context {
+	if p == nil || len(p.vars) == 0 {
+		return ctx
+	}
+	return execx.WithOptions(ctx, p.Option())
+}
@@ -0,0 +1,72 @@
+package envx
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "REGISTRY=ghcr.io/dev\nNAMESPACE=dev\nFROM_SHELL=file\n")
+	writeFile(t, dir, ".env.prod", "REGISTRY=ghcr.io/prod\nIMAGE=${REGISTRY}/app\n")
+	writeFile(t, dir, ".env.prod.local", "NAMESPACE=mine\n")
+	t.Setenv("FROM_SHELL", "shell")
+
+	p, err := Load(dir, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, ".env"), filepath.Join(dir, ".env.prod"), filepath.Join(dir, ".env.prod.local")}; !slices.Equal(p.Files, want) {
+		t.Errorf("Files = %q, want %q", p.Files, want)
+	}
+	want := []string{"FROM_SHELL=shell", "IMAGE=ghcr.io/prod/app", "NAMESPACE=mine", "REGISTRY=ghcr.io/prod"}
+	if got := p.Environ(); !slices.Equal(got, want) {
+		t.Errorf("Environ() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadMissingProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "A=1\n")
+	if _, err := Load(dir, "staging"); err == nil || !strings.Contains(err.Error(), `profile "staging" not found`) {
+		t.Errorf("Load() error = %v, want profile not found", err)
+	}
+	if _, err := Load(dir, "../x"); err == nil {
+		t.Error("Load() with a path as name error = nil")
+	}
+	p, err := Load(t.TempDir(), "")
+	if err != nil || len(p.Files) != 0 {
+		t.Errorf("Load() of an empty directory = %+v, %v", p, err)
+	}
+}
+
+func TestProfileOption(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "SECRET_TOKEN=abc\nA=profile\n")
+	p, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := execx.Options{Env: []string{"A=explicit"}}.With(p.Option())
+	if want := []string{"A=explicit"}; !slices.Equal(o.Env, want) {
+		t.Errorf("Env = %q, want %q", o.Env, want)
+	}
+	if want := []string{"A=profile", "SECRET_TOKEN=abc"}; !slices.Equal(o.BaseEnv, want) {
+		t.Errorf("BaseEnv = %q, want %q", o.BaseEnv, want)
+	}
+	if line, want := execx.CommandLine(o, "env"), "A=explicit SECRET_TOKEN=abc env"; line != want {
+		t.Errorf("CommandLine() = %q, want %q", line, want)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
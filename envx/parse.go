@@ -0,0 +1,194 @@
+package envx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Lookup returns the value of a variable a ${VAR} reference expands to
+type Lookup func(name string) (string, bool)
+
+// Var is one assignment of a .env file
+type Var struct {
+	Name  string
+	Value string
+	Line  int // Line number in the file
+}
+
+// Parse reads assignments in .env syntax from r: KEY=VALUE lines, optionally
+// starting with "export", with # comments and blank lines ignored. Values in
+// single quotes are literal. Unquoted values and values in double quotes
+// expand ${VAR} and ${VAR:-default} with lookup, which also sees the
+// assignments before them in r; a double-quoted value also understands \n,
+// \t, \", \\ and \$. Referencing a variable that is not set is an error.
+func Parse(r io.Reader, lookup Lookup) ([]Var, error) {
+	var vars []Var
+	defined := make(map[string]string)
+	err := parse(r, func(name string) (string, bool) {
+		if v, ok := defined[name]; ok {
+			return v, true
+		}
+		if lookup != nil {
+			return lookup(name)
+		}
+		return "", false
+	}, func(v Var) {
+		defined[v.Name] = v.Value
+		vars = append(vars, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// parse reads assignments from r, expanding references with resolve, and
+// hands each to define before the next line is read
+func parse(r io.Reader, resolve Lookup, define func(Var)) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, raw, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validName(name) {
+			return fmt.Errorf("line %d: want NAME=VALUE, got %q", n, line)
+		}
+		value, err := parseValue(strings.TrimSpace(raw), resolve)
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", n, name, err)
+		}
+		define(Var{Name: name, Value: value, Line: n})
+	}
+	return scanner.Err()
+}
+
+// parseValue unquotes and expands the right-hand side of an assignment
+func parseValue(raw string, lookup Lookup) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		if err := trailing(raw[end+2:]); err != nil {
+			return "", err
+		}
+		return raw[1 : end+1], nil
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; c {
+			case '"':
+				if err := trailing(raw[i+1:]); err != nil {
+					return "", err
+				}
+				return b.String(), nil
+			case '\\':
+				if i+1 == len(raw) {
+					return "", fmt.Errorf("unterminated double quote")
+				}
+				i++
+				switch e := raw[i]; e {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\', '$':
+					b.WriteByte(e)
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(e)
+				}
+			case '$':
+				expanded, n, err := expandRef(raw[i:], lookup)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(expanded)
+				i += n - 1
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	}
+	// An unquoted value ends at a comment preceded by whitespace
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return Expand(raw, lookup)
+}
+
+// trailing checks that only a comment follows a closing quote
+func trailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after closing quote", rest)
+	}
+	return nil
+}
+
+// Expand replaces ${VAR} and ${VAR:-default} in s with values from lookup.
+// A $ not followed by { is kept as is.
+func Expand(s string, lookup Lookup) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		expanded, n, err := expandRef(s[i:], lookup)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(expanded)
+		i += n - 1
+	}
+	return b.String(), nil
+}
+
+// expandRef expands the reference s starts with and returns its length
+func expandRef(s string, lookup Lookup) (string, int, error) {
+	if !strings.HasPrefix(s, "${") {
+		return "$", 1, nil
+	}
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return "", 0, fmt.Errorf("unterminated reference %q", s)
+	}
+	name, fallback, hasDefault := strings.Cut(s[2:end], ":-")
+	if !validName(name) {
+		return "", 0, fmt.Errorf("invalid reference %q", s[:end+1])
+	}
+	if lookup != nil {
+		if v, ok := lookup(name); ok && (v != "" || !hasDefault) {
+			return v, end + 1, nil
+		}
+	}
+	if !hasDefault {
+		return "", 0, fmt.Errorf("${%s} is not set", name)
+	}
+	return fallback, end + 1, nil
+}
+
+// validName reports whether name can be the name of a shell variable
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
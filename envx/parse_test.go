@@ -0,0 +1,117 @@
+package envx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		switch name {
+		case "HOME":
+			return "/home/me", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{name: "plain", in: "A=1\nB=two words", want: []string{"A=1", "B=two words"}},
+		{name: "comments and blanks", in: "# comment\n\n  A=1 # trailing\n", want: []string{"A=1"}},
+		{name: "export", in: "export A=1", want: []string{"A=1"}},
+		{name: "spaces around equals", in: "A = 1", want: []string{"A=1"}},
+		{name: "empty value", in: "A=", want: []string{"A="}},
+		{name: "hash inside value", in: "URL=http://x/#frag", want: []string{"URL=http://x/#frag"}},
+		{name: "single quotes are literal", in: `A='${HOME} \n # x'`, want: []string{`A=${HOME} \n # x`}},
+		{name: "double quote escapes", in: `A="a\nb\t\"c\" \\ \$HOME \x"`, want: []string{"A=a\nb\t\"c\" \\ $HOME \\x"}},
+		{name: "expands lookup", in: "A=${HOME}/bin", want: []string{"A=/home/me/bin"}},
+		{name: "expands in double quotes", in: `A="${HOME} x"`, want: []string{"A=/home/me x"}},
+		{name: "earlier assignments", in: "A=1\nB=${A}2", want: []string{"A=1", "B=12"}},
+		{name: "assignment shadows lookup", in: "HOME=/h\nB=${HOME}", want: []string{"HOME=/h", "B=/h"}},
+		{name: "default", in: "A=${UNSET:-fallback}", want: []string{"A=fallback"}},
+		{name: "default for empty", in: "A=${EMPTY:-fallback}", want: []string{"A=fallback"}},
+		{name: "empty without default", in: "A=${EMPTY}", want: []string{"A="}},
+		{name: "bare dollar", in: "A=$HOME $", want: []string{"A=$HOME $"}},
+		{name: "comment after quote", in: `A="x" # note`, want: []string{"A=x"}},
+		{name: "unset reference", in: "A=${UNSET}", wantErr: "line 1: A: ${UNSET} is not set"},
+		{name: "missing equals", in: "A=1\nB", wantErr: "line 2: want NAME=VALUE"},
+		{name: "invalid name", in: "1A=x", wantErr: "want NAME=VALUE"},
+		{name: "unterminated single", in: "A='x", wantErr: "unterminated single quote"},
+		{name: "unterminated double", in: `A="x`, wantErr: "unterminated double quote"},
+		{name: "text after quote", in: `A="x" y`, wantErr: `unexpected "y" after closing quote`},
+		{name: "unterminated reference", in: "A=${HOME", wantErr: "unterminated reference"},
+		{name: "invalid reference", in: "A=${1X}", wantErr: "invalid reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := Parse(strings.NewReader(tt.in), lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var got []string
+			for _, v := range vars {
+				got = append(got, v.Name+"="+v.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLineNumbers(t *testing.T) {
+	vars, err := Parse(strings.NewReader("# header\n\nA=1\nB=2\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 || vars[0].Line != 3 || vars[1].Line != 4 {
+		t.Errorf("Parse() = %+v, want A on line 3 and B on line 4", vars)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "NAME" {
+			return "app", true
+		}
+		return "", false
+	}
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "", want: ""},
+		{in: "no refs", want: "no refs"},
+		{in: "${NAME}-${NAME}", want: "app-app"},
+		{in: "${UNSET:-}", want: ""},
+		{in: "${UNSET:-a b}", want: "a b"},
+		{in: "$NAME", want: "$NAME"},
+		{in: "${UNSET}", wantErr: true},
+		{in: "${NAME", wantErr: true},
+		{in: "${}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.in, lookup)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expand(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
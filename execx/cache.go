@@ -0,0 +1,409 @@
+package execx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// CacheDirEnv is the environment variable overriding where cache manifests are stored
+const CacheDirEnv = "EXECX_CACHE_DIR"
+
+// NoCacheEnv is the environment variable that makes CacheInterceptor run every
+// command, e.g. in release pipelines. Manifests are still recorded.
+const NoCacheEnv = "EXECX_NO_CACHE"
+
+// DefaultCacheDir is where cache manifests are stored, relative to the working directory
+const DefaultCacheDir = ".cache/execx"
+
+// CacheSpec declares what a command reads and writes, so it can be skipped
+// when none of it changed since it last succeeded
+type CacheSpec struct {
+	// Inputs are globs of the files the command reads, relative to its working
+	// directory. "**" matches any number of directories and a directory stands
+	// for all files below it.
+	Inputs  []string
+	Env     []string          // Names of environment variables the outputs depend on
+	Keys    map[string]string // Other values the outputs depend on, e.g. the toolchain version
+	Outputs []string          // Files or directories the command writes
+}
+
+// Cached lets a CacheInterceptor skip the command when its inputs, the
+// environment variables and keys it declares and its outputs are unchanged
+func Cached(spec CacheSpec) Option {
+	return func(o *Options) {
+		o.Cache = &spec
+	}
+}
+
+// cacheManifest records the state of a command after it last succeeded.
+// Environment variables and keys are stored as hashes, since they may hold secrets.
+type cacheManifest struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Inputs  map[string]string `json:"inputs"`
+	Env     map[string]string `json:"env"`
+	Keys    map[string]string `json:"keys,omitempty"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// CacheInterceptor skips Run invocations declaring a CacheSpec whose content
+// hashes match the manifest recorded when the same command last succeeded.
+// Unlike modification times, content hashes survive a git checkout.
+// Manifests are stored in dir, or in DefaultCacheDir or CacheDirEnv when empty.
+func CacheInterceptor(dir string) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			spec := inv.Options.Cache
+			if spec == nil || inv.Capture {
+				return next(ctx, inv)
+			}
+
+			cacheDir := dir
+			if cacheDir == "" {
+				cacheDir = cacheDirFromEnv()
+			}
+			file := filepath.Join(cacheDir, cacheKey(inv)+".json")
+
+			current, err := snapshotInputs(inv, cacheDir)
+			if err != nil {
+				logx.FromContext(ctx).WarnContext(ctx, "⚠️ Cannot hash command inputs, running without cache", "command", inv.Command, "err", err)
+				return next(ctx, inv)
+			}
+
+			if skip, _ := strconv.ParseBool(os.Getenv(NoCacheEnv)); !skip {
+				reason := current.changedSince(file, inv)
+				if reason == "" {
+					logx.FromContext(ctx).InfoContext(ctx, "⏭️ Skipping command, inputs and outputs unchanged", "command", inv.Command, "manifest", file)
+					return &Result{Command: inv.Command, Args: inv.Args, Cached: true}, nil
+				}
+				logx.FromContext(ctx).DebugContext(ctx, "Cache miss", "command", inv.Command, "reason", reason)
+			}
+
+			// A failed or interrupted run must not leave the previous manifest behind
+			_ = os.Remove(file)
+			res, err := next(ctx, inv)
+			if err != nil {
+				return res, err
+			}
+
+			if current.Outputs, err = hashPaths(workDir(inv), spec.Outputs, nil, true); err != nil {
+				logx.FromContext(ctx).WarnContext(ctx, "⚠️ Command outputs not recorded in cache", "command", inv.Command, "err", err)
+				return res, nil
+			}
+			if err := current.save(file); err != nil {
+				logx.FromContext(ctx).WarnContext(ctx, "⚠️ Failed to write cache manifest", "manifest", file, "err", err)
+			}
+			return res, nil
+		}
+	}
+}
+
+// cacheDirFromEnv returns CacheDirEnv or DefaultCacheDir
+func cacheDirFromEnv() string {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	return DefaultCacheDir
+}
+
+// cacheKey identifies an invocation by its command line, directory and environment
+func cacheKey(inv *Invocation) string {
+	h := sha256.New()
+	for _, s := range append([]string{inv.Command, workDir(inv)}, inv.Args...) {
+		fmt.Fprintf(h, "%q\n", s)
+	}
+	env := slices.Sorted(slices.Values(inv.Options.Env))
+	for _, kv := range env {
+		fmt.Fprintf(h, "env %q\n", kv)
+	}
+	name := strings.TrimSuffix(filepath.Base(inv.Command), filepath.Ext(inv.Command))
+	return name + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// workDir returns the absolute working directory of an invocation
+func workDir(inv *Invocation) string {
+	dir, err := filepath.Abs(inv.Options.Dir)
+	if err != nil {
+		return inv.Options.Dir
+	}
+	return dir
+}
+
+// snapshotInputs hashes the inputs and environment declared by inv
+func snapshotInputs(inv *Invocation, cacheDir string) (*cacheManifest, error) {
+	// Outputs inside an input directory, e.g. dist/ below ".", are not inputs
+	skip := []string{cacheDir}
+	for _, output := range inv.Options.Cache.Outputs {
+		if !filepath.IsAbs(output) {
+			output = filepath.Join(workDir(inv), output)
+		}
+		skip = append(skip, output)
+	}
+	inputs, err := hashPaths(workDir(inv), inv.Options.Cache.Inputs, skip, false)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string, len(inv.Options.Cache.Env))
+	for _, name := range inv.Options.Cache.Env {
+		value, ok := lookupEnv(inv.Options.environment(), name)
+		if !ok {
+			env[name] = "unset"
+			continue
+		}
+		env[name] = hashString(value)
+	}
+	var keys map[string]string
+	if len(inv.Options.Cache.Keys) > 0 {
+		keys = make(map[string]string, len(inv.Options.Cache.Keys))
+		for name, value := range inv.Options.Cache.Keys {
+			keys[name] = hashString(value)
+		}
+	}
+	return &cacheManifest{
+		Command: inv.Command,
+		Args:    inv.Args,
+		Inputs:  inputs,
+		Env:     env,
+		Keys:    keys,
+	}, nil
+}
+
+// hashString returns the hex encoded SHA-256 of s
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookupEnv returns a variable from the invocation's environment, then from ours
+func lookupEnv(env []string, name string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if key, value, _ := strings.Cut(env[i], "="); key == name {
+			return value, true
+		}
+	}
+	return os.LookupEnv(name)
+}
+
+// changedSince describes why m differs from the manifest in file, or returns
+// an empty string if inputs, environment and outputs are all unchanged
+func (m *cacheManifest) changedSince(file string, inv *Invocation) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "no manifest"
+	}
+	var recorded cacheManifest
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return "unreadable manifest"
+	}
+	if reason := diffHashes("input", recorded.Inputs, m.Inputs); reason != "" {
+		return reason
+	}
+	if reason := diffHashes("environment variable", recorded.Env, m.Env); reason != "" {
+		return reason
+	}
+	if reason := diffHashes("key", recorded.Keys, m.Keys); reason != "" {
+		return reason
+	}
+	outputs, err := hashPaths(workDir(inv), inv.Options.Cache.Outputs, nil, true)
+	if err != nil {
+		return "output missing"
+	}
+	return diffHashes("output", recorded.Outputs, outputs)
+}
+
+// diffHashes names the first entry that differs between two hash maps
+func diffHashes(kind string, recorded, current map[string]string) string {
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		if recorded[name] != current[name] {
+			return kind + " changed: " + name
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(recorded)) {
+		if _, ok := current[name]; !ok {
+			return kind + " removed: " + name
+		}
+	}
+	return ""
+}
+
+// save writes the manifest atomically
+func (m *cacheManifest) save(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".manifest-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// hashPaths returns the SHA-256 of every file matched by patterns, keyed by
+// its slash-separated path relative to base. Directories are walked, leaving
+// out .git and the paths in skip. With mustExist, a pattern matching nothing is an error.
+func hashPaths(base string, patterns, skip []string, mustExist bool) (map[string]string, error) {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		if abs, err := filepath.Abs(path); err == nil {
+			skipped[abs] = true
+		}
+	}
+	hashes := make(map[string]string)
+	for _, pattern := range patterns {
+		matches, err := expandGlob(base, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 && mustExist {
+			return nil, fmt.Errorf("%s: %w", pattern, fs.ErrNotExist)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if skipped[path] || path != match && d.IsDir() && d.Name() == ".git" {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() {
+					return nil
+				}
+				sum, err := hashFile(path)
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(base, path)
+				if err != nil {
+					rel = path
+				}
+				hashes[filepath.ToSlash(rel)] = sum
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// hashFile returns the hex encoded SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// expandGlob returns the existing paths matching pattern relative to base.
+// Unlike filepath.Glob, "**" matches any number of directories.
+func expandGlob(base, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(base, pattern)
+	}
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	full := filepath.ToSlash(pattern)
+	re, err := globRegexp(full)
+	if err != nil {
+		return nil, err
+	}
+	root := full[:strings.Index(full, "**")]
+	if i := strings.IndexAny(root, "*?["); i >= 0 {
+		root = root[:i]
+	}
+	root = filepath.FromSlash(root[:strings.LastIndex(root, "/")+1])
+
+	var matches []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && re.MatchString(filepath.ToSlash(path)) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// globRegexp translates a slash-separated glob with "**" into a regular expression
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unterminated [", glob)
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
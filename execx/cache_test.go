@@ -0,0 +1,114 @@
+package execx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		match []string
+		skip  []string
+	}{
+		{"**/*.go", []string{"main.go", "a/b/c.go"}, []string{"main.go.orig", "a/b/c.txt"}},
+		{"src/**", []string{"src/a", "src/a/b"}, []string{"src", "other/a"}},
+		{"src/**/x.yaml", []string{"src/x.yaml", "src/a/b/x.yaml"}, []string{"x.yaml", "src/ax.yaml"}},
+		{"*.txt", []string{"a.txt"}, []string{"a/b.txt"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file.txt", "file12.txt", "file/.txt"}},
+		{"[ab].txt", []string{"a.txt", "b.txt"}, []string{"c.txt"}},
+		{"[^ab].txt", []string{"c.txt"}, []string{"a.txt"}},
+		{"a+b(1).txt", []string{"a+b(1).txt"}, []string{"aab1.txt"}},
+	}
+	for _, tt := range tests {
+		re, err := globRegexp(tt.glob)
+		if err != nil {
+			t.Errorf("globRegexp(%q) error = %v", tt.glob, err)
+			continue
+		}
+		for _, path := range tt.match {
+			if !re.MatchString(path) {
+				t.Errorf("globRegexp(%q) does not match %q", tt.glob, path)
+			}
+		}
+		for _, path := range tt.skip {
+			if re.MatchString(path) {
+				t.Errorf("globRegexp(%q) matches %q", tt.glob, path)
+			}
+		}
+	}
+}
+
+func TestGlobRegexpInvalid(t *testing.T) {
+	if _, err := globRegexp("a[bc"); err == nil {
+		t.Error("globRegexp(\"a[bc\") error = nil, want unterminated [")
+	}
+}
+
+func TestCacheInterceptor(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "app")
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app/main.go", "package main")
+	write("lib/lib.go", "package lib")
+	t.Setenv(NoCacheEnv, "")
+
+	runs := 0
+	build := func(ctx context.Context, inv *Invocation) (*Result, error) {
+		runs++
+		return &Result{}, os.WriteFile(filepath.Join(dir, "app.bin"), []byte("binary"), 0o644)
+	}
+	handler := CacheInterceptor(filepath.Join(root, "cache"))(build)
+	run := func(version string) bool {
+		t.Helper()
+		before := runs
+		inv := &Invocation{Command: "go", Args: []string{"build"}, Options: Options{
+			Dir: dir,
+			Cache: &CacheSpec{
+				Inputs:  []string{"**/*.go", "../lib/**/*.go"},
+				Keys:    map[string]string{"GOVERSION": version},
+				Outputs: []string{"app.bin"},
+			},
+		}}
+		ctx := logx.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if _, err := handler(ctx, inv); err != nil {
+			t.Fatal(err)
+		}
+		return runs > before
+	}
+
+	if !run("go1.25.0") {
+		t.Error("first build was skipped")
+	}
+	if run("go1.25.0") {
+		t.Error("unchanged build was not skipped")
+	}
+	if !run("go1.25.1") {
+		t.Error("build with another toolchain was skipped")
+	}
+	write("lib/lib.go", "package lib // changed")
+	if !run("go1.25.1") {
+		t.Error("build with a changed input outside its directory was skipped")
+	}
+	if err := os.Remove(filepath.Join(dir, "app.bin")); err != nil {
+		t.Fatal(err)
+	}
+	if !run("go1.25.1") {
+		t.Error("build with a missing output was skipped")
+	}
+}
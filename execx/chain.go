@@ -0,0 +1,341 @@
+package execx
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// Invocation describes a single command execution as seen by interceptors
+type Invocation struct {
+	Command     string
+	Args        []string
+	StreamToLog bool    // Run: output is sent to slog instead of the terminal
+	Capture     bool    // Output is collected into the Result
+	Tee         bool    // Capture: output is also copied to the terminal
+	Options     Options // Per-invocation settings; interceptors may change them
+
+	pipe *os.File // Stdout of a Pipeline stage feeding the next one, replacing the terminal
+}
+
+// Handler executes an invocation
+type Handler func(ctx context.Context, inv *Invocation) (*Result, error)
+
+// Interceptor wraps a Handler with cross-cutting behavior.
+// It sees the invocation before execution and the result after it.
+type Interceptor func(next Handler) Handler
+
+// Chain is an Executor that passes every command through a stack of interceptors
+type Chain struct {
+	base    Executor
+	handler Handler
+}
+
+// NewChain creates a Chain that executes commands with base.
+// Interceptors run in the order given: the first one sees the invocation
+// first and the result last.
+func NewChain(base Executor, interceptors ...Interceptor) *Chain {
+	h := HandlerFor(base)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		h = interceptors[i](h)
+	}
+	return &Chain{base: base, handler: h}
+}
+
+// IsDryRun reports whether the chain executes commands with a DryRun
+func (c *Chain) IsDryRun() bool {
+	return IsDryRun(c.base)
+}
+
+// Run executes a command through the interceptors
+func (c *Chain) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	_, err := c.handler(ctx, &Invocation{
+		Command:     command,
+		Args:        args,
+		StreamToLog: streamToLog,
+		Options:     OptionsFrom(ctx),
+	})
+	return err
+}
+
+// Capture executes a command through the interceptors and returns its captured output
+func (c *Chain) Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	return c.handler(ctx, &Invocation{
+		Command: command,
+		Args:    args,
+		Capture: true,
+		Tee:     tee,
+		Options: OptionsFrom(ctx),
+	})
+}
+
+// invoker is implemented by executors that return the full Result of any invocation
+type invoker interface {
+	execute(ctx context.Context, inv *Invocation) (*Result, error)
+}
+
+// execute passes inv through the interceptors, keeping the full Result
+func (c *Chain) execute(ctx context.Context, inv *Invocation) (*Result, error) {
+	return c.handler(ctx, inv)
+}
+
+// HandlerFor adapts an Executor to a Handler.
+// Capturing invocations require the executor to implement Capturer.
+func HandlerFor(e Executor) Handler {
+	return func(ctx context.Context, inv *Invocation) (*Result, error) {
+		if x, ok := e.(invoker); ok {
+			return x.execute(ctx, inv)
+		}
+		ctx = contextWithOptions(ctx, inv.Options)
+		if inv.Capture {
+			capturer, ok := e.(Capturer)
+			if !ok {
+				return &Result{Command: inv.Command, Args: inv.Args, ExitCode: -1},
+					fmt.Errorf("executor %T does not support capturing output", e)
+			}
+			return capturer.Capture(ctx, inv.Command, inv.Tee, inv.Args...)
+		}
+
+		start := time.Now()
+		err := e.Run(ctx, inv.Command, inv.StreamToLog, inv.Args...)
+		return &Result{
+			Command:  inv.Command,
+			Args:     inv.Args,
+			ExitCode: exitCode(err),
+			Duration: time.Since(start),
+		}, err
+	}
+}
+
+// LoggingInterceptor logs every command before it starts and when it finishes
+func LoggingInterceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			line := inv.Options.Redactor.Redact(CommandLine(inv.Options, inv.Command, inv.Args...))
+			logx.FromContext(ctx).InfoContext(ctx, "🔧 Executing", "command", line)
+
+			res, err := next(ctx, inv)
+			var usage []any
+			if res != nil && res.Usage != nil {
+				usage = []any{"usage", res.Usage}
+			}
+			if err != nil {
+				attrs := []any{
+					"command", inv.Command,
+					"exitCode", exitCode(err),
+					"err", inv.Options.Redactor.Redact(err.Error()),
+				}
+				logx.FromContext(ctx).ErrorContext(ctx, "❌ Command failed", append(attrs, usage...)...)
+				return res, err
+			}
+			attrs := []any{"command", inv.Command}
+			if res != nil {
+				attrs = append(attrs, "duration", res.Duration)
+			}
+			logx.FromContext(ctx).InfoContext(ctx, "✅ Command finished", append(attrs, usage...)...)
+			return res, nil
+		}
+	}
+}
+
+// UsageInterceptor logs the duration and resource usage of every command
+// that ran, so the steps blowing past memory limits can be told apart
+func UsageInterceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			res, err := next(ctx, inv)
+			if res != nil && res.Usage != nil {
+				logx.FromContext(ctx).InfoContext(ctx, "📊 Command finished",
+					"command", metricsKey(inv),
+					logx.KeyDuration, res.Duration,
+					"usage", res.Usage,
+				)
+			}
+			return res, err
+		}
+	}
+}
+
+// TimingInterceptor reports the wall time of every command to observe
+func TimingInterceptor(observe func(inv *Invocation, elapsed time.Duration)) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			start := time.Now()
+			res, err := next(ctx, inv)
+			elapsed := time.Since(start)
+			if res != nil && res.Duration == 0 {
+				res.Duration = elapsed
+			}
+			observe(inv, elapsed)
+			return res, err
+		}
+	}
+}
+
+// RedactionInterceptor masks the secrets known to r in command echoes,
+// captured output and error messages produced further down the chain
+func RedactionInterceptor(r *Redactor) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			inv.Options = inv.Options.With(RedactWith(r))
+			res, err := next(ctx, inv)
+			if res != nil {
+				res.Stdout = []byte(r.Redact(string(res.Stdout)))
+				res.Stderr = []byte(r.Redact(string(res.Stderr)))
+				for i := range res.Lines {
+					res.Lines[i].Text = r.Redact(res.Lines[i].Text)
+				}
+			}
+			var cmdErr *CommandError
+			if errors.As(err, &cmdErr) {
+				cmdErr.redact(r)
+			}
+			return res, r.RedactError(err)
+		}
+	}
+}
+
+// DefaultRedactionInterceptor masks secrets like RedactionInterceptor with
+// DefaultRedactor, for invocations whose options set no Redactor
+func DefaultRedactionInterceptor() Interceptor {
+	redaction := RedactionInterceptor(defaultRedactor)
+	return func(next Handler) Handler {
+		redacted := redaction(next)
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			if inv.Options.Redactor != nil {
+				return next(ctx, inv)
+			}
+			return redacted(ctx, inv)
+		}
+	}
+}
+
+// RetryInterceptor retries failed commands using the policy selected by policyFor
+func RetryInterceptor(policyFor PolicyFunc) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			return retry(ctx, policyFor(inv.Command, inv.Args), inv, next)
+		}
+	}
+}
+
+// CommandStats holds aggregated measurements for one command
+type CommandStats struct {
+	Calls    int
+	Failures int
+	Total    time.Duration
+	Max      time.Duration
+	CPU      time.Duration // Total user and system CPU time
+	MaxRSS   int64         // Largest peak resident set size in bytes
+}
+
+// Metrics aggregates command counts and durations, keyed by command and subcommand
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[string]*CommandStats
+}
+
+// NewMetrics creates an empty Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{stats: make(map[string]*CommandStats)}
+}
+
+// Snapshot returns a copy of the collected statistics
+func (m *Metrics) Snapshot() map[string]CommandStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]CommandStats, len(m.stats))
+	for k, v := range m.stats {
+		snapshot[k] = *v
+	}
+	return snapshot
+}
+
+var defaultMetrics = NewMetrics()
+
+// DefaultMetrics returns the process-wide Metrics NewDefaultExecutor records into
+func DefaultMetrics() *Metrics {
+	return defaultMetrics
+}
+
+// LogHeaviest logs the statistics of up to n commands by decreasing peak memory
+func (m *Metrics) LogHeaviest(ctx context.Context, n int) {
+	snapshot := m.Snapshot()
+	for i, key := range m.Heaviest(n) {
+		s := snapshot[key]
+		logx.FromContext(ctx).InfoContext(ctx, fmt.Sprintf("🏋️ Heaviest command #%d", i+1),
+			"command", key,
+			"calls", s.Calls,
+			"maxRSS", s.MaxRSS,
+			"cpu", s.CPU,
+			"total", s.Total,
+		)
+	}
+}
+
+// Heaviest returns up to n command keys ordered by decreasing peak memory
+func (m *Metrics) Heaviest(n int) []string {
+	snapshot := m.Snapshot()
+	keys := slices.Collect(maps.Keys(snapshot))
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(snapshot[b].MaxRSS, snapshot[a].MaxRSS), cmp.Compare(a, b))
+	})
+	return keys[:min(n, len(keys))]
+}
+
+// observe records one finished command
+func (m *Metrics) observe(key string, elapsed time.Duration, usage *Usage, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.stats[key]
+	if !ok {
+		s = &CommandStats{}
+		m.stats[key] = s
+	}
+	s.Calls++
+	s.Total += elapsed
+	s.Max = max(s.Max, elapsed)
+	if usage != nil {
+		s.CPU += usage.CPUTime()
+		s.MaxRSS = max(s.MaxRSS, usage.MaxRSS)
+	}
+	if failed {
+		s.Failures++
+	}
+}
+
+// MetricsInterceptor records every command into m
+func MetricsInterceptor(m *Metrics) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			start := time.Now()
+			res, err := next(ctx, inv)
+			var usage *Usage
+			if res != nil {
+				usage = res.Usage
+			}
+			m.observe(metricsKey(inv), time.Since(start), usage, err != nil)
+			return res, err
+		}
+	}
+}
+
+// metricsKey groups commands by name and first non-flag argument, e.g. "helm upgrade".
+// The name leaves out the directory, so a pinned bin/helm is still "helm".
+func metricsKey(inv *Invocation) string {
+	name := filepath.Base(inv.Command)
+	if len(inv.Args) > 0 && !strings.HasPrefix(inv.Args[0], "-") {
+		return name + " " + inv.Args[0]
+	}
+	return name
+}
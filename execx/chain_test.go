@@ -0,0 +1,119 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runFunc is an Executor that only implements Run
+type runFunc func(ctx context.Context, command string, args ...string) error
+
+// Run implements Executor
+func (f runFunc) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	return f(ctx, command, args...)
+}
+
+func TestChainOrder(t *testing.T) {
+	var events []string
+	trace := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, inv *Invocation) (*Result, error) {
+				events = append(events, name+" before")
+				inv.Options = inv.Options.With(Env(name + "=1"))
+				res, err := next(ctx, inv)
+				events = append(events, name+" after")
+				return res, err
+			}
+		}
+	}
+	var env []string
+	base := runFunc(func(ctx context.Context, command string, args ...string) error {
+		events = append(events, "run "+command)
+		env = OptionsFrom(ctx).Env
+		return nil
+	})
+
+	if err := NewChain(base, trace("outer"), trace("inner")).Run(context.Background(), "helm", false, "list"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"outer before", "inner before", "run helm", "inner after", "outer after"}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if want := []string{"outer=1", "inner=1"}; !slices.Equal(env, want) {
+		t.Errorf("base executor saw Env %q, want %q set by the interceptors", env, want)
+	}
+}
+
+func TestChainNested(t *testing.T) {
+	var seen []string
+	record := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, inv *Invocation) (*Result, error) {
+				seen = append(seen, name)
+				return next(ctx, inv)
+			}
+		}
+	}
+	dry := NewDryRunWithWriter(&strings.Builder{}).Respond(&Result{Command: "git", Stdout: []byte("abc\n")})
+	inner := NewChain(dry, record("inner"))
+	res, err := NewChain(inner, record("outer")).Capture(context.Background(), "git", false, "rev-parse")
+	if err != nil || string(res.Stdout) != "abc\n" {
+		t.Errorf("Capture() = %q, %v, want the canned stdout", res.Stdout, err)
+	}
+	if !slices.Equal(seen, []string{"outer", "inner"}) {
+		t.Errorf("interceptors ran as %q, want outer then inner", seen)
+	}
+}
+
+func TestHandlerForCaptureUnsupported(t *testing.T) {
+	base := runFunc(func(ctx context.Context, command string, args ...string) error { return nil })
+	res, err := NewChain(base).Capture(context.Background(), "helm", false, "list")
+	if err == nil || res.ExitCode != -1 {
+		t.Errorf("Capture() = %+v, %v, want an error from an executor that cannot capture", res, err)
+	}
+}
+
+func TestTimingInterceptor(t *testing.T) {
+	base := runFunc(func(ctx context.Context, command string, args ...string) error {
+		time.Sleep(10 * time.Millisecond)
+		return &CommandError{Command: command, ExitCode: 3, Err: exitStatus(3)}
+	})
+	var observed time.Duration
+	chain := NewChain(base, TimingInterceptor(func(inv *Invocation, elapsed time.Duration) { observed = elapsed }))
+	err := chain.Run(context.Background(), "helm", false, "lint")
+	if exitCode(err) != 3 {
+		t.Errorf("Run() error = %v, want exit code 3 passed through", err)
+	}
+	if observed < 10*time.Millisecond {
+		t.Errorf("observed %s, want at least the 10ms the command ran", observed)
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	m := NewMetrics()
+	failing := errors.New("exit status 1")
+	base := runFunc(func(ctx context.Context, command string, args ...string) error {
+		if slices.Contains(args, "--fail") {
+			return failing
+		}
+		return nil
+	})
+	chain := NewChain(base, MetricsInterceptor(m))
+	ctx := context.Background()
+	_ = chain.Run(ctx, "/proj/bin/helm", false, "upgrade", "app")
+	_ = chain.Run(ctx, "helm", false, "upgrade", "--fail")
+	_ = chain.Run(ctx, "helm", false, "--debug", "list")
+
+	snapshot := m.Snapshot()
+	if s := snapshot["helm upgrade"]; s.Calls != 2 || s.Failures != 1 {
+		t.Errorf(`stats["helm upgrade"] = %+v, want 2 calls and 1 failure`, s)
+	}
+	if s := snapshot["helm"]; s.Calls != 1 {
+		t.Errorf(`stats["helm"] = %+v, want the flag-first call`, s)
+	}
+}
@@ -0,0 +1,154 @@
+package execx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+// DryRunEnv is the environment variable that makes NewDefaultExecutor return a DryRun
+const DryRunEnv = "EXECX_DRY_RUN"
+
+// DryRun is an Executor that prints commands instead of executing them.
+// Each command is written as a copy-pasteable shell line including its
+// working directory and environment overrides.
+type DryRun struct {
+	out       iox.Writer
+	mu        sync.Mutex
+	responses []*Result
+}
+
+// NewDryRun creates a new DryRun that writes commands to stderr
+func NewDryRun() *DryRun {
+	return NewDryRunWithWriter(os.Stderr)
+}
+
+// NewDryRunWithWriter creates a new DryRun that writes commands to out
+func NewDryRunWithWriter(out iox.Writer) *DryRun {
+	return &DryRun{out: out}
+}
+
+// IsDryRun reports that commands are only printed
+func (d *DryRun) IsDryRun() bool {
+	return true
+}
+
+// DryRunner is implemented by executors that know whether they only print commands
+type DryRunner interface {
+	IsDryRun() bool
+}
+
+// IsDryRun reports whether executor only prints commands instead of running them,
+// e.g. a DryRun or a Chain executing commands with one
+func IsDryRun(executor Executor) bool {
+	d, ok := executor.(DryRunner)
+	return ok && d.IsDryRun()
+}
+
+// Respond registers a canned result for commands whose argv starts with
+// res.Command followed by res.Args. Later registrations take precedence.
+func (d *DryRun) Respond(res *Result) *DryRun {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.responses = append(d.responses, res)
+	return d
+}
+
+// Run prints the command and returns the canned outcome, success by default
+func (d *DryRun) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	_, err := d.Capture(ctx, command, false, args...)
+	return err
+}
+
+// Capture prints the command and returns the canned result, empty by default
+func (d *DryRun) Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	opts := OptionsFrom(ctx)
+	line := opts.Redactor.Redact(CommandLine(opts, command, args...))
+
+	d.mu.Lock()
+	_, _ = fmt.Fprintf(d.out, "%s  # dry-run\n", line)
+	canned := d.lookup(command, args)
+	d.mu.Unlock()
+
+	res := &Result{Command: command, Args: args}
+	if canned == nil {
+		return res, nil
+	}
+
+	res.Stdout = canned.Stdout
+	res.Stderr = canned.Stderr
+	res.ExitCode = canned.ExitCode
+	if tee {
+		_, _ = os.Stdout.Write(res.Stdout)
+		_, _ = os.Stderr.Write(res.Stderr)
+	}
+	if opts.Stdout != nil {
+		_, _ = opts.Stdout.Write(res.Stdout)
+	}
+	if opts.Stderr != nil {
+		_, _ = opts.Stderr.Write(res.Stderr)
+	}
+	if res.ExitCode != 0 {
+		cmdErr := &CommandError{
+			Command:    command,
+			Args:       args,
+			Dir:        opts.Dir,
+			Env:        opts.environment(),
+			ExitCode:   res.ExitCode,
+			StderrTail: tailLines(string(res.Stderr), DefaultStderrTailLines),
+			Err:        exitStatus(res.ExitCode),
+		}
+		cmdErr.redact(opts.Redactor)
+		return res, cmdErr
+	}
+	return res, nil
+}
+
+// lookup returns the most recently registered response matching argv
+func (d *DryRun) lookup(command string, args []string) *Result {
+	argv := append([]string{command}, args...)
+	for i := len(d.responses) - 1; i >= 0; i-- {
+		prefix := d.responses[i].Argv()
+		if len(prefix) <= len(argv) && slices.Equal(prefix, argv[:len(prefix)]) {
+			return d.responses[i]
+		}
+	}
+	return nil
+}
+
+// DryRunEnabled reports whether DryRunEnv is set to a true value
+func DryRunEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(DryRunEnv))
+	return enabled
+}
+
+// NewDefaultExecutor returns the executor runners use by default.
+// It is a DryRun when DryRunEnv is set to a true value, an Exec otherwise.
+// Commands are recorded as spans when DefaultTracer is enabled, and commands
+// declaring a CacheSpec are skipped when unchanged, except in a dry run.
+// Commands that run log their resource usage and are recorded in DefaultMetrics.
+// Secrets are masked with DefaultRedactor unless the options set a Redactor.
+func NewDefaultExecutor() Executor {
+	if DryRunEnabled() {
+		return NewChain(NewDryRun(), DefaultRedactionInterceptor(), TracingInterceptor(nil))
+	}
+	return NewChain(NewExec(), DefaultRedactionInterceptor(), TracingInterceptor(nil), MetricsInterceptor(DefaultMetrics()), UsageInterceptor(), CacheInterceptor(""))
+}
+
+// exitStatus is a synthetic exit code error
+type exitStatus int
+
+// Error implements the error interface
+func (e exitStatus) Error() string {
+	return "exit status " + strconv.Itoa(int(e))
+}
+
+// ExitCode returns the exit code
+func (e exitStatus) ExitCode() int {
+	return int(e)
+}
@@ -0,0 +1,86 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIsDryRun(t *testing.T) {
+	dry := NewDryRun()
+	tests := []struct {
+		name     string
+		executor Executor
+		want     bool
+	}{
+		{"dry run", dry, true},
+		{"chain", NewChain(dry, TracingInterceptor(nil)), true},
+		{"retry", NewRetry(NewChain(dry), DefaultRetryPolicy), true},
+		{"exec", NewExec(), false},
+		{"chain of exec", NewChain(NewExec()), false},
+	}
+	for _, tt := range tests {
+		if got := IsDryRun(tt.executor); got != tt.want {
+			t.Errorf("IsDryRun(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDryRunShowsBaseEnv(t *testing.T) {
+	var out bytes.Buffer
+	dry := NewDryRunWithWriter(&out).Respond(&Result{Command: "helm", Args: []string{"lint"}, ExitCode: 1})
+	executor := NewChain(dry, DefaultRedactionInterceptor())
+	ctx := WithOptions(context.Background(), BaseEnv("PROFILE=dev", "API_TOKEN=abc"), Env("HELM_DEBUG=1"))
+
+	err := executor.Run(ctx, "helm", false, "lint")
+	want := "PROFILE=dev API_TOKEN=*** HELM_DEBUG=1 helm lint"
+	if got := out.String(); got != want+"  # dry-run\n" {
+		t.Errorf("dry run printed %q, want %q", got, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "reproduce: "+want) {
+		t.Errorf("Run() error = %v, want to reproduce %q", err, want)
+	}
+}
+
+func TestDryRunRespond(t *testing.T) {
+	var out bytes.Buffer
+	dry := NewDryRunWithWriter(&out).
+		Respond(&Result{Command: "git", Stdout: []byte("any\n")}).
+		Respond(&Result{Command: "git", Args: []string{"rev-parse"}, Stdout: []byte("abc123\n")}).
+		Respond(&Result{Command: "helm", Args: []string{"lint"}, Stderr: []byte("[ERROR] Chart.yaml\n"), ExitCode: 2})
+	tests := []struct {
+		argv   []string
+		stdout string
+		exit   int
+	}{
+		{[]string{"git", "rev-parse", "HEAD"}, "abc123\n", 0},
+		{[]string{"git", "status"}, "any\n", 0},
+		{[]string{"helm", "lint", "."}, "", 2},
+		{[]string{"helm", "list"}, "", 0},
+	}
+	for _, tt := range tests {
+		var stdout bytes.Buffer
+		ctx := WithOptions(context.Background(), TeeStdout(&stdout))
+		res, err := dry.Capture(ctx, tt.argv[0], false, tt.argv[1:]...)
+		if string(res.Stdout) != tt.stdout || stdout.String() != tt.stdout {
+			t.Errorf("Capture(%q) stdout = %q, wrote %q, want %q", tt.argv, res.Stdout, stdout.String(), tt.stdout)
+		}
+		if got := exitCode(err); got != tt.exit || res.ExitCode != tt.exit {
+			t.Errorf("Capture(%q) exit code = %d, error = %v, want %d", tt.argv, res.ExitCode, err, tt.exit)
+		}
+	}
+	want := "git rev-parse HEAD  # dry-run\ngit status  # dry-run\nhelm lint .  # dry-run\nhelm list  # dry-run\n"
+	if out.String() != want {
+		t.Errorf("dry run printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestNewDefaultExecutorDryRun(t *testing.T) {
+	for value, want := range map[string]bool{"1": true, "true": true, "0": false, "": false, "yes": false} {
+		t.Setenv(DryRunEnv, value)
+		if got := IsDryRun(NewDefaultExecutor()); got != want {
+			t.Errorf("%s=%q: IsDryRun(NewDefaultExecutor()) = %v, want %v", DryRunEnv, value, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultStderrTailLines is how many trailing stderr lines a CommandError keeps
+const DefaultStderrTailLines = 20
+
+// CommandError is returned when a started command does not exit successfully
+type CommandError struct {
+	Command    string
+	Args       []string
+	Dir        string        // Working directory; empty means the current directory
+	Env        []string      // Variables set on top of the inherited environment
+	ExitCode   int           // Exit code, or -1 if the command did not exit normally
+	Signal     string        // Signal that terminated the command, if any
+	Duration   time.Duration // Wall time from start to exit
+	StderrTail []string      // Last lines written to stderr
+	Err        error         // Underlying error from the process or the context
+}
+
+// Error implements the error interface.
+// The message holds the command line to run it again and ends with the
+// stderr tail, so failure summaries explain themselves.
+func (e *CommandError) Error() string {
+	verb := "failed"
+	if e.Canceled() {
+		verb = "canceled"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "command %q %s", e.Command, verb)
+	if e.Duration > 0 {
+		fmt.Fprintf(&b, " after %s", e.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	b.WriteString("\nreproduce: " + e.CommandLine())
+
+	if len(e.StderrTail) > 0 {
+		fmt.Fprintf(&b, "\nstderr (last %d lines):", len(e.StderrTail))
+		for _, line := range e.StderrTail {
+			b.WriteString("\n  " + line)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Canceled reports whether the command was stopped by its context
+func (e *CommandError) Canceled() bool {
+	return errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// Argv returns the full argument vector, command included
+func (e *CommandError) Argv() []string {
+	return append([]string{e.Command}, e.Args...)
+}
+
+// CommandLine renders the command as a POSIX shell line to paste into a terminal
+func (e *CommandError) CommandLine() string {
+	return CommandLine(Options{Dir: e.Dir, Env: e.Env}, e.Command, e.Args...)
+}
+
+// newCommandError builds the error for a command that finished unsuccessfully,
+// with the secrets known to the options' Redactor masked.
+// When ctx is done, the context error replaces the process error as the cause.
+func newCommandError(ctx context.Context, res *Result, err error, opts Options, tail []string) *CommandError {
+	cause := err
+	if ctx.Err() != nil {
+		cause = canceledCause(ctx, opts.Timeout)
+	}
+	cmdErr := &CommandError{
+		Command:    res.Command,
+		Args:       res.Args,
+		Dir:        opts.Dir,
+		Env:        opts.environment(),
+		ExitCode:   res.ExitCode,
+		Signal:     exitSignal(err),
+		Duration:   res.Duration,
+		StderrTail: tail,
+		Err:        cause,
+	}
+	cmdErr.redact(opts.Redactor)
+	return cmdErr
+}
+
+// redact masks the secrets known to r in the arguments, environment and stderr tail
+func (e *CommandError) redact(r *Redactor) {
+	if r == nil {
+		return
+	}
+	e.Args = r.RedactArgs(e.Args)
+	env := make([]string, len(e.Env))
+	for i, kv := range e.Env {
+		env[i] = r.Redact(kv)
+	}
+	e.Env = env
+	e.StderrTail = r.RedactArgs(e.StderrTail)
+}
+
+// canceledCause explains why a command was stopped by its context
+func canceledCause(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrTerminalInput) {
+		return cause
+	}
+	if cause != nil && cause != ctx.Err() {
+		return fmt.Errorf("%w (%v)", ctx.Err(), cause)
+	}
+	return ctx.Err()
+}
+
+// exitSignal returns the name of the signal that terminated the process, if any
+func exitSignal(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ""
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
+
+// exitCode extracts the process exit code from an error returned by Wait
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -1,15 +1,16 @@
 package execx
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log/slog"
 	"os"
 	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/vinaycharlie01/go-mage-shared/iox"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
 )
 
 // Commander defines the interface for command execution
@@ -43,9 +44,40 @@ type Executor interface {
 	Run(ctx context.Context, command string, streamToLog bool, args ...string) error
 }
 
+// Capturer defines the interface for executing commands and capturing their output
+type Capturer interface {
+	Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error)
+}
+
+// Result holds the outcome of a finished command
+type Result struct {
+	Command  string        // Command that was executed
+	Args     []string      // Arguments passed to the command
+	Stdout   []byte        // Captured standard output
+	Stderr   []byte        // Captured standard error
+	ExitCode int           // Exit code, or -1 if the command did not exit normally
+	Duration time.Duration // Wall time from start to exit
+	Lines    []Line        // Stdout and stderr lines in arrival order, see OrderedOutput
+	Usage    *Usage        // Resources consumed by the process, nil when unavailable
+	Cached   bool          // Skipped by a CacheInterceptor because nothing changed
+}
+
+// Argv returns the full argument vector, command included
+func (r *Result) Argv() []string {
+	return append([]string{r.Command}, r.Args...)
+}
+
+// Success reports whether the command exited with code 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0
+}
+
 // ExecCmd wraps *exec.Cmd to implement the Commander interface
 type ExecCmd struct {
 	*exec.Cmd
+	redactor *Redactor
+	env      []string   // Variables set for this command on top of the inherited environment
+	kill     *killTimer // SIGKILL scheduled by a cancellation
 }
 
 // CombinedOutput wraps the underlying command's CombinedOutput
@@ -88,14 +120,68 @@ func (e *ExecCmd) StdoutPipe() (iox.ReadCloser, error) {
 	return e.Cmd.StdoutPipe()
 }
 
-// String wraps the underlying command's String
+// String renders the command with its directory and environment as a POSIX
+// shell line, masking secrets when the command was created with a Redactor
+// in its options
 func (e *ExecCmd) String() string {
-	return e.Cmd.String()
+	return e.redactor.Redact(CommandLine(Options{Dir: e.Dir, Env: e.env}, e.Path, e.Args[1:]...))
 }
 
-// Wait wraps the underlying command's Wait
+// Wait wraps the underlying command's Wait. A SIGKILL still scheduled for the
+// command is called off, unless processes it spawned are left in its group.
 func (e *ExecCmd) Wait() error {
-	return e.Cmd.Wait()
+	err := e.Cmd.Wait()
+	e.kill.reaped()
+	return err
+}
+
+// killTimer is the SIGKILL a canceled command gets when its grace period ends
+type killTimer struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+	// lingering reports whether processes the command spawned outlive it; nil
+	// when the SIGKILL only addresses the command itself
+	lingering func() bool
+}
+
+// arm runs kill after grace unless stop is called first
+func (k *killTimer) arm(grace time.Duration, kill func()) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.stopped {
+		return
+	}
+	k.timer = time.AfterFunc(grace, func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if !k.stopped {
+			kill()
+		}
+	})
+}
+
+// reaped is called once the command has been waited for. Its id may be reused
+// from now on, so the SIGKILL is called off unless it addresses a process
+// group that still has members: the group id stays taken while it does.
+func (k *killTimer) reaped() {
+	if k == nil || k.lingering != nil && k.lingering() {
+		return
+	}
+	k.stop()
+}
+
+// stop calls off the pending SIGKILL, if any
+func (k *killTimer) stop() {
+	if k == nil {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stopped = true
+	if k.timer != nil {
+		k.timer.Stop()
+	}
 }
 
 // SetStdin sets the standard input for the command
@@ -126,15 +212,27 @@ func (e *ExecCmd) SetEnv(env []string) {
 // DefaultCommandCreator is the default implementation of CommandCreator
 type DefaultCommandCreator struct{}
 
-// CommandContext creates a new ExecCmd
+// CommandContext creates a new ExecCmd.
+// When ctx is canceled, the command and the processes it spawned receive
+// SIGTERM, then SIGKILL after the grace period from the options in ctx.
 func (d *DefaultCommandCreator) CommandContext(ctx context.Context, name string, args ...string) Commander {
 	cmd := exec.CommandContext(ctx, name, args...)
-	return &ExecCmd{Cmd: cmd}
+
+	opts := OptionsFrom(ctx)
+	grace := opts.GracePeriod
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	interactive := !opts.suppliesStdin() && opts.StdinPolicy.inherits() && isTerminal(os.Stdin.Fd())
+	kill := configureTermination(cmd, grace, interactive)
+
+	return &ExecCmd{Cmd: cmd, redactor: opts.Redactor, env: opts.environment(), kill: kill}
 }
 
 // Exec is the default implementation of Executor
 type Exec struct {
 	creator CommandCreator
+	stdin   StdinPolicy
 }
 
 // NewExec creates a new Exec instance with the default command creator
@@ -151,75 +249,142 @@ func NewExecWithCreator(creator CommandCreator) *Exec {
 	}
 }
 
+// WithStdinPolicy sets what commands read when their options give no stdin,
+// instead of DefaultStdinPolicy
+func (e *Exec) WithStdinPolicy(p StdinPolicy) *Exec {
+	e.stdin = p
+	return e
+}
+
 // Run executes a command and streams its output.
 // If streamToLog is true, output is sent to slog; otherwise, to terminal.
+// Per-invocation settings are taken from ctx, see WithOptions.
 func (e *Exec) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
-	cmd := e.creator.CommandContext(ctx, command, args...)
+	_, err := e.execute(ctx, &Invocation{
+		Command:     command,
+		Args:        args,
+		StreamToLog: streamToLog,
+		Options:     OptionsFrom(ctx),
+	})
+	return err
+}
 
-	// Set stdin using the interface method
-	cmd.SetStdin(os.Stdin)
+// Capture executes a command and returns its captured output.
+// If tee is true, output is also copied live to the terminal.
+// On failure, the returned Result is still populated alongside the error.
+func (e *Exec) Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	return e.execute(ctx, &Invocation{
+		Command: command,
+		Args:    args,
+		Capture: true,
+		Tee:     tee,
+		Options: OptionsFrom(ctx),
+	})
+}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+// execute runs the invocation and waits for its output to be fully consumed.
+// All output, including an unterminated last line, is written before it returns.
+func (e *Exec) execute(ctx context.Context, inv *Invocation) (*Result, error) {
+	res := &Result{Command: inv.Command, Args: inv.Args, ExitCode: -1}
+	start := time.Now()
+
+	opts := inv.Options
+	opts.StdinPolicy = opts.StdinPolicy.or(e.stdin)
+	ctx = contextWithOptions(ctx, opts)
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	// Nothing is started once the caller gave up, e.g. for the next step of a canceled target
+	if ctx.Err() != nil {
+		return res, newCommandError(ctx, res, ctx.Err(), opts, nil)
 	}
+	// Canceled with ErrTerminalInput when the command waits for the terminal
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	cmd := e.creator.CommandContext(ctx, inv.Command, inv.Args...)
+
+	configureCommand(ctx, cmd, inv.Command, opts)
+	cmd.SetStdin(opts.stdin())
+
+	tail := newLineTail(max(opts.stderrTailLines(), 1))
+
+	// The command copies into these writers itself, so Wait returns only
+	// once all output has been consumed. None of them ever blocks or fails,
+	// so the child can never stall on a full pipe.
+	out := newOutputs(ctx, inv, tail, newPTY(ctx, cmd, inv))
+	out.connect(cmd)
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command %q: %w", command, err)
-	}
-
-	if streamToLog {
-		go streamToSlog(ctx, stdout, slog.LevelInfo)
-		go streamToSlog(ctx, stderr, slog.LevelError)
-	} else {
-		go func() {
-			_, _ = io.Copy(os.Stdout, stdout)
-		}()
-		go func() {
-			_, _ = io.Copy(os.Stderr, stderr)
-		}()
-	}
-
-	// Wait for the command to finish execution
-	if err := cmd.Wait(); err != nil {
-		// if context was canceled, wrap cleanly
-		if ctx.Err() != nil {
-			return fmt.Errorf("command %q canceled: %w", command, ctx.Err())
+		out.flush()
+		return res, fmt.Errorf("failed to start command %q: %w", inv.Command, err)
+	}
+	out.started()
+	defer watchTerminalInput(cmd, stop)()
+
+	return res, wait(ctx, cmd, inv, res, out, tail, start)
+}
+
+// wait waits for a started command and fills res from its outcome and output
+func wait(ctx context.Context, cmd Commander, inv *Invocation, res *Result, out *outputs, tail *lineTail, start time.Time) error {
+	err := cmd.Wait()
+	out.flush()
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The command succeeded but a process it spawned kept the output open
+		logx.FromContext(ctx).WarnContext(ctx, "⚠️ Command exited with its output still open by a background process",
+			"command", inv.Command,
+		)
+		err = nil
+	}
+
+	opts := inv.Options
+	res.Duration = time.Since(start)
+	res.ExitCode = exitCode(err)
+	res.Lines = out.lines
+	if reporter, ok := cmd.(usageReporter); ok {
+		res.Usage = reporter.Usage()
+	}
+	if inv.Capture {
+		res.Stdout = []byte(opts.Redactor.Redact(out.stdoutBuf.String()))
+		res.Stderr = []byte(opts.Redactor.Redact(out.stderrBuf.String()))
+	}
+
+	if err != nil {
+		// if context was canceled, newCommandError reports it as the cause
+		var lines []string
+		if opts.stderrTailLines() > 0 {
+			lines = tail.Lines()
 		}
-		return fmt.Errorf("command %q failed: %w", command, err)
+		return newCommandError(ctx, res, err, opts, lines)
 	}
 
 	return nil
 }
 
+// configureCommand applies the working directory and environment from opts
+func configureCommand(ctx context.Context, cmd Commander, command string, opts Options) {
+	if opts.Dir != "" {
+		cmd.SetDir(opts.Dir)
+	}
+	switch {
+	case opts.hermetic():
+		environ := MergeEnv(hermeticEnviron(ctx, cmd.Environ(), command, opts), opts.BaseEnv)
+		cmd.SetEnv(MergeEnv(environ, opts.Env))
+	case len(opts.Env) > 0 || len(opts.BaseEnv) > 0:
+		cmd.SetEnv(MergeEnv(MergeEnv(cmd.Environ(), opts.BaseEnv), opts.Env))
+	}
+}
+
 // Run is a package-level convenience function that uses the default Exec implementation
 func Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
 	e := NewExec()
 	return e.Run(ctx, command, streamToLog, args...)
 }
 
-// streamToSlog reads command output and logs it to slog with the given level.
-func streamToSlog(ctx context.Context, r iox.Reader, level slog.Level) {
-	scanner := bufio.NewScanner(r)
-	const maxCapacity = 1024 * 1024 // 1 MB max line size
-	buf := make([]byte, 64*1024)    // 64 KB initial buffer
-	scanner.Buffer(buf, maxCapacity)
-
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			slog.WarnContext(ctx, "stream canceled", "reason", ctx.Err())
-			return
-		default:
-			slog.Log(ctx, level, scanner.Text())
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		slog.ErrorContext(ctx, "failed to read stream", "err", err)
-	}
+// Capture is a package-level convenience function that uses the default Exec implementation
+func Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	e := NewExec()
+	return e.Capture(ctx, command, tee, args...)
 }
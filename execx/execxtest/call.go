@@ -0,0 +1,160 @@
+package execxtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+// Call is an expected command and its scripted outcome
+type Call struct {
+	command  string
+	args     []string
+	dir      string
+	env      []string
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+	times    int
+	after    []*Call
+	called   int
+}
+
+// Stdout sets the output the command writes to stdout
+func (c *Call) Stdout(s string) *Call {
+	c.stdout = s
+	return c
+}
+
+// Stderr sets the output the command writes to stderr
+func (c *Call) Stderr(s string) *Call {
+	c.stderr = s
+	return c
+}
+
+// ExitCode sets the exit code the command finishes with
+func (c *Call) ExitCode(code int) *Call {
+	c.exitCode = code
+	return c
+}
+
+// Err makes the command fail with err instead of an exit code
+func (c *Call) Err(err error) *Call {
+	c.err = err
+	return c
+}
+
+// Times sets how many times the command is expected to run
+func (c *Call) Times(n int) *Call {
+	c.times = n
+	return c
+}
+
+// After requires the given calls to be satisfied before this one runs
+func (c *Call) After(calls ...*Call) *Call {
+	c.after = append(c.after, calls...)
+	return c
+}
+
+// Dir requires the command to run in the given working directory
+func (c *Call) Dir(dir string) *Call {
+	c.dir = dir
+	return c
+}
+
+// Env requires the given KEY=VALUE pairs among the command's environment overrides
+func (c *Call) Env(env ...string) *Call {
+	c.env = append(c.env, env...)
+	return c
+}
+
+// Argv returns the expected argument vector, command included
+func (c *Call) Argv() []string {
+	return append([]string{c.command}, c.args...)
+}
+
+// matches reports whether inv satisfies the expectation
+func (c *Call) matches(inv Invocation) bool {
+	if !commandMatches(c.command, inv.Command) || !slices.Equal(c.args, inv.Args) {
+		return false
+	}
+	if c.dir != "" && c.dir != inv.Dir {
+		return false
+	}
+	return len(missingEnv(c.env, inv.Env)) == 0
+}
+
+// commandMatches reports whether the command got is the one expected. A bare
+// name such as "helm" also matches a path to it, e.g. the pinned bin/helm
+// runners execute once it is installed.
+func commandMatches(want, got string) bool {
+	if want == got {
+		return true
+	}
+	if strings.ContainsAny(want, `/\`) {
+		return false
+	}
+	return want == strings.TrimSuffix(filepath.Base(got), ".exe")
+}
+
+// unmetPrerequisites lists the calls declared with After that are not yet satisfied
+func (c *Call) unmetPrerequisites() []string {
+	var missing []string
+	for _, p := range c.after {
+		if p.called < p.times {
+			missing = append(missing, formatArgv(p.command, p.args))
+		}
+	}
+	return missing
+}
+
+// result returns the error the scripted command finishes with
+func (c *Call) result(command string, args []string) error {
+	if c.err == nil && c.exitCode == 0 {
+		return nil
+	}
+	cmdErr := &execx.CommandError{
+		Command:    command,
+		Args:       args,
+		ExitCode:   c.exitCode,
+		StderrTail: tail(c.stderr, execx.DefaultStderrTailLines),
+		Err:        c.err,
+	}
+	if c.err == nil {
+		cmdErr.Err = &ExitError{Code: c.exitCode}
+	} else if c.exitCode == 0 {
+		cmdErr.ExitCode = -1
+	}
+	return cmdErr
+}
+
+// tail returns the last max lines of s
+func tail(s string, max int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+	}
+	return lines
+}
+
+// ExitError reports a scripted non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the scripted exit code
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
@@ -0,0 +1,212 @@
+package execxtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+// Cmd is a scripted Commander returned by Fake.CommandContext
+type Cmd struct {
+	fake *Fake
+	ctx  context.Context
+	name string
+	args []string
+
+	dir    string
+	env    []string
+	stdin  iox.Reader
+	stdout iox.Writer
+	stderr iox.Writer
+
+	stdoutPipe *io.PipeWriter
+	stderrPipe *io.PipeWriter
+
+	started bool
+	done    chan struct{}
+	err     error
+	mu      sync.Mutex
+}
+
+// CombinedOutput runs the command and returns stdout and stderr together
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	var buf bytes.Buffer
+	c.stdout = &buf
+	c.stderr = &buf
+	err := c.Run()
+	return buf.Bytes(), err
+}
+
+// Environ returns the environment the command would run with
+func (c *Cmd) Environ() []string {
+	if c.env != nil {
+		return append([]string(nil), c.env...)
+	}
+	return os.Environ()
+}
+
+// Output runs the command and returns its stdout
+func (c *Cmd) Output() ([]byte, error) {
+	var buf bytes.Buffer
+	c.stdout = &buf
+	err := c.Run()
+	return buf.Bytes(), err
+}
+
+// Run starts the command and waits for it to finish
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start matches the command against the script and begins producing output
+func (c *Cmd) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return errors.New("execxtest: already started")
+	}
+	c.started = true
+
+	inv := Invocation{Command: c.name, Args: c.args, Dir: c.dir, Env: c.envOverrides()}
+	call, seq, err := c.fake.match(inv)
+	if err != nil {
+		return err
+	}
+
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		// stdin may be fed through StdinPipe after Start, so read it here
+		if c.stdin != nil {
+			data, _ := io.ReadAll(c.stdin)
+			c.fake.recordStdin(seq, data)
+		}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.emit(c.stdout, c.stdoutPipe, call.stdout)
+		}()
+		go func() {
+			defer wg.Done()
+			c.emit(c.stderr, c.stderrPipe, call.stderr)
+		}()
+		wg.Wait()
+		if call.err != nil {
+			c.err = call.err
+		} else if call.exitCode != 0 {
+			c.err = &ExitError{Code: call.exitCode}
+		}
+		if c.ctx.Err() != nil {
+			c.err = c.ctx.Err()
+		}
+	}()
+	return nil
+}
+
+// emit writes scripted output to the configured writer or pipe
+func (c *Cmd) emit(w iox.Writer, pipe *io.PipeWriter, s string) {
+	switch {
+	case pipe != nil:
+		_, _ = io.WriteString(pipe, s)
+		_ = pipe.Close()
+	case w != nil:
+		_, _ = io.WriteString(w, s)
+	}
+}
+
+// envOverrides returns the entries of the command environment not inherited unchanged
+func (c *Cmd) envOverrides() []string {
+	if c.env == nil {
+		return nil
+	}
+	inherited := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		inherited[kv] = true
+	}
+	var overrides []string
+	for _, kv := range c.env {
+		if !inherited[kv] {
+			overrides = append(overrides, kv)
+		}
+	}
+	return overrides
+}
+
+// StderrPipe returns a pipe connected to the scripted stderr
+func (c *Cmd) StderrPipe() (iox.ReadCloser, error) {
+	r, w := io.Pipe()
+	c.stderrPipe = w
+	return r, nil
+}
+
+// StdinPipe returns a pipe whose content is recorded as the command's stdin
+func (c *Cmd) StdinPipe() (iox.WriteCloser, error) {
+	r, w := io.Pipe()
+	c.stdin = r
+	return w, nil
+}
+
+// StdoutPipe returns a pipe connected to the scripted stdout
+func (c *Cmd) StdoutPipe() (iox.ReadCloser, error) {
+	r, w := io.Pipe()
+	c.stdoutPipe = w
+	return r, nil
+}
+
+// String returns the command line
+func (c *Cmd) String() string {
+	return formatArgv(c.name, c.args)
+}
+
+// Wait waits for the scripted command to finish
+func (c *Cmd) Wait() error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done == nil {
+		return errors.New("execxtest: not started")
+	}
+	<-done
+	return c.err
+}
+
+// SetStdin sets the standard input for the command
+func (c *Cmd) SetStdin(stdin iox.Reader) {
+	if stdin == os.Stdin {
+		// Never block a test on the terminal
+		return
+	}
+	c.stdin = stdin
+}
+
+// SetStdout sets the standard output for the command
+func (c *Cmd) SetStdout(stdout iox.Writer) {
+	c.stdout = stdout
+}
+
+// SetStderr sets the standard error for the command
+func (c *Cmd) SetStderr(stderr iox.Writer) {
+	c.stderr = stderr
+}
+
+// SetDir sets the working directory for the command
+func (c *Cmd) SetDir(dir string) {
+	c.dir = dir
+}
+
+// SetEnv sets the environment variables for the command
+func (c *Cmd) SetEnv(env []string) {
+	c.env = env
+}
+
+var _ execx.Commander = (*Cmd)(nil)
@@ -0,0 +1,43 @@
+package execxtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff renders a line-oriented diff between two argument vectors.
+// Removed entries are marked with "-", added entries with "+".
+func Diff(want, got []string) string {
+	// Longest common subsequence table
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			fmt.Fprintf(&b, "      %q\n", want[i])
+			i++
+			j++
+		case i < len(want) && (j == len(got) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&b, "    - %q\n", want[i])
+			i++
+		default:
+			fmt.Fprintf(&b, "    + %q\n", got[j])
+			j++
+		}
+	}
+	return b.String()
+}
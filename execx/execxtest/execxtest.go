@@ -0,0 +1,225 @@
+// Package execxtest provides scripted fakes for testing code built on execx.
+//
+// A Fake implements execx.Executor, execx.Capturer and execx.CommandCreator,
+// so it can be passed to runner constructors such as
+// helmx.NewHelmRunnerWithExecutor or to execx.NewExecWithCreator.
+package execxtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+// Fake is a scripted Executor and CommandCreator
+type Fake struct {
+	t       testing.TB
+	mu      sync.Mutex
+	calls   []*Call
+	ordered bool
+	seen    []Invocation
+}
+
+// Invocation records a command received by a Fake
+type Invocation struct {
+	Command string
+	Args    []string
+	Dir     string
+	Env     []string // Environment overrides from execx options, BaseEnv included
+	Stdin   []byte   // Standard input consumed by the command
+}
+
+// New creates a Fake bound to t.
+// When the test finishes, t fails if any expected call was not made.
+func New(t testing.TB) *Fake {
+	f := &Fake{t: t}
+	t.Cleanup(f.verify)
+	return f
+}
+
+// InOrder requires expected calls to be made in the order they were declared
+func (f *Fake) InOrder() *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ordered = true
+	return f
+}
+
+// Expect declares a call the code under test must make
+func (f *Fake) Expect(command string, args ...string) *Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := &Call{command: command, args: args, times: 1}
+	f.calls = append(f.calls, c)
+	return c
+}
+
+// Invocations returns every command received so far, in order
+func (f *Fake) Invocations() []Invocation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Invocation(nil), f.seen...)
+}
+
+// Run implements execx.Executor
+func (f *Fake) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	_, err := f.Capture(ctx, command, false, args...)
+	return err
+}
+
+// Capture implements execx.Capturer
+func (f *Fake) Capture(ctx context.Context, command string, tee bool, args ...string) (*execx.Result, error) {
+	opts := execx.OptionsFrom(ctx)
+	inv := Invocation{Command: command, Args: args, Dir: opts.Dir, Env: execx.MergeEnv(opts.BaseEnv, opts.Env)}
+	switch {
+	case opts.Stdin != nil:
+		inv.Stdin, _ = io.ReadAll(opts.Stdin)
+	case opts.StdinData != nil:
+		inv.Stdin = append([]byte{}, opts.StdinData...)
+	}
+
+	c, _, err := f.match(inv)
+	res := &execx.Result{Command: command, Args: args, ExitCode: -1}
+	if err != nil {
+		return res, err
+	}
+
+	res.Stdout = []byte(c.stdout)
+	res.Stderr = []byte(c.stderr)
+	res.ExitCode = c.exitCode
+	if tee {
+		_, _ = io.WriteString(os.Stdout, c.stdout)
+		_, _ = io.WriteString(os.Stderr, c.stderr)
+	}
+	if opts.Stdout != nil {
+		_, _ = io.WriteString(opts.Stdout, c.stdout)
+	}
+	if opts.Stderr != nil {
+		_, _ = io.WriteString(opts.Stderr, c.stderr)
+	}
+	return res, c.result(command, args)
+}
+
+// CommandContext implements execx.CommandCreator
+func (f *Fake) CommandContext(ctx context.Context, name string, args ...string) execx.Commander {
+	return &Cmd{fake: f, ctx: ctx, name: name, args: args}
+}
+
+// match finds the expectation satisfied by inv and records the invocation.
+// The returned index identifies the recorded invocation.
+func (f *Fake) match(inv Invocation) (*Call, int, error) {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	seq := len(f.seen)
+	f.seen = append(f.seen, inv)
+
+	var pending []*Call
+	for _, c := range f.calls {
+		if c.called < c.times {
+			pending = append(pending, c)
+		}
+	}
+	if f.ordered && len(pending) > 0 {
+		pending = pending[:1]
+	}
+
+	for _, c := range pending {
+		if !c.matches(inv) {
+			continue
+		}
+		if missing := c.unmetPrerequisites(); len(missing) > 0 {
+			err := fmt.Errorf("execxtest: %s called before %s", formatArgv(inv.Command, inv.Args), strings.Join(missing, ", "))
+			f.t.Errorf("%v", err)
+			return nil, seq, err
+		}
+		c.called++
+		return c, seq, nil
+	}
+
+	err := fmt.Errorf("execxtest: unexpected command %s", formatArgv(inv.Command, inv.Args))
+	f.t.Errorf("%s", unexpectedMessage(inv, closest(pending, inv)))
+	return nil, seq, err
+}
+
+// recordStdin attaches stdin read after matching to a recorded invocation
+func (f *Fake) recordStdin(seq int, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seen[seq].Stdin = data
+}
+
+// verify fails the test for every expectation that was not met
+func (f *Fake) verify() {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c.called < c.times {
+			f.t.Errorf("execxtest: missing call %s (called %d of %d times)", formatArgv(c.command, c.args), c.called, c.times)
+		}
+	}
+}
+
+// closest picks the pending expectation most similar to inv, preferring the same command
+func closest(pending []*Call, inv Invocation) *Call {
+	for _, c := range pending {
+		if commandMatches(c.command, inv.Command) {
+			return c
+		}
+	}
+	if len(pending) > 0 {
+		return pending[0]
+	}
+	return nil
+}
+
+// unexpectedMessage renders a readable explanation of a failed match
+func unexpectedMessage(inv Invocation, want *Call) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "execxtest: unexpected command\n  got:  %s\n", formatArgv(inv.Command, inv.Args))
+	if want == nil {
+		b.WriteString("  want: no more commands")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  want: %s\n", formatArgv(want.command, want.args))
+	if want.dir != "" && want.dir != inv.Dir {
+		fmt.Fprintf(&b, "  dir:  got %q, want %q\n", inv.Dir, want.dir)
+	}
+	for _, kv := range missingEnv(want.env, inv.Env) {
+		fmt.Fprintf(&b, "  env:  missing %q\n", kv)
+	}
+	b.WriteString("  argv diff (-want +got):\n")
+	b.WriteString(Diff(want.Argv(), append([]string{inv.Command}, inv.Args...)))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatArgv renders a command line for messages
+func formatArgv(command string, args []string) string {
+	return execx.ShellJoin(append([]string{command}, args...))
+}
+
+// missingEnv returns the entries of want not present in got
+func missingEnv(want, got []string) []string {
+	var missing []string
+	for _, kv := range want {
+		found := false
+		for _, g := range got {
+			if g == kv {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, kv)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,81 @@
+package execxtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+func TestCommandMatches(t *testing.T) {
+	tests := []struct {
+		want, got string
+		match     bool
+	}{
+		{"helm", "helm", true},
+		{"helm", "/proj/bin/helm", true},
+		{"helm", "bin/helm.exe", true},
+		{"helm", "/usr/bin/helm3", false},
+		{"helm", "helm-diff", false},
+		{"/proj/bin/helm", "/proj/bin/helm", true},
+		{"/proj/bin/helm", "/usr/bin/helm", false},
+		{"/proj/bin/helm", "helm", false},
+	}
+	for _, tt := range tests {
+		if got := commandMatches(tt.want, tt.got); got != tt.match {
+			t.Errorf("commandMatches(%q, %q) = %v, want %v", tt.want, tt.got, got, tt.match)
+		}
+	}
+}
+
+func TestFakeCapture(t *testing.T) {
+	f := New(t).InOrder()
+	f.Expect("git", "rev-parse", "HEAD").Stdout("abc123\n")
+	f.Expect("kubectl", "apply", "-f", "-").Dir("deploy").Env("KUBECONFIG=/tmp/kc", "PROFILE=dev")
+
+	ctx := context.Background()
+	res, err := f.Capture(ctx, "/usr/bin/git", false, "rev-parse", "HEAD")
+	if err != nil || string(res.Stdout) != "abc123\n" {
+		t.Fatalf("Capture() = %q, %v", res.Stdout, err)
+	}
+
+	ctx = execx.WithOptions(ctx, execx.Dir("deploy"), execx.Env("KUBECONFIG=/tmp/kc"), execx.BaseEnv("PROFILE=dev"), execx.StdinBytes([]byte("kind: Pod\n")))
+	if err := f.Run(ctx, "kubectl", false, "apply", "-f", "-"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.Invocations()[1].Stdin); got != "kind: Pod\n" {
+		t.Errorf("recorded stdin = %q", got)
+	}
+}
+
+func TestFakeExitCode(t *testing.T) {
+	f := New(t)
+	f.Expect("helm", "lint").Stderr("line 1\nError: bad chart\n").ExitCode(2)
+
+	err := f.Run(context.Background(), "helm", false, "lint")
+	cmdErr, ok := err.(*execx.CommandError)
+	if !ok {
+		t.Fatalf("Run() error = %T %v, want *execx.CommandError", err, err)
+	}
+	if cmdErr.ExitCode != 2 || !strings.Contains(strings.Join(cmdErr.StderrTail, "\n"), "bad chart") {
+		t.Errorf("CommandError = %+v", cmdErr)
+	}
+}
+
+func TestUnexpectedMessage(t *testing.T) {
+	want := &Call{command: "helm", args: []string{"upgrade", "app", "."}, dir: "charts", env: []string{"A=1"}}
+	msg := unexpectedMessage(Invocation{Command: "helm", Args: []string{"upgrade", "web", "."}}, want)
+	for _, part := range []string{
+		"got:  helm upgrade web .",
+		"want: helm upgrade app .",
+		`dir:  got "", want "charts"`,
+		`env:  missing "A=1"`,
+		`- "app"`,
+		`+ "web"`,
+	} {
+		if !strings.Contains(msg, part) {
+			t.Errorf("message lacks %q:\n%s", part, msg)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package execxtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+// RecordEnv is the environment variable that switches Golden into record mode
+const RecordEnv = "EXECXTEST_RECORD"
+
+// Transcript is the golden file format holding recorded invocations
+type Transcript struct {
+	Calls []TranscriptCall `json:"calls"`
+}
+
+// TranscriptCall is a single recorded invocation and its outcome
+type TranscriptCall struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args,omitempty"`
+	Dir      string   `json:"dir,omitempty"`
+	Env      []string `json:"env,omitempty"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exitCode,omitempty"`
+}
+
+// Recorder runs commands through a real executor and records them into a transcript
+type Recorder struct {
+	t      testing.TB
+	real   execx.Capturer
+	path   string
+	mu     sync.Mutex
+	record Transcript
+}
+
+// Record creates a Recorder that executes commands with real and writes the
+// transcript to path when the test finishes.
+func Record(t testing.TB, path string, real execx.Capturer) *Recorder {
+	r := &Recorder{t: t, real: real, path: path}
+	t.Cleanup(func() {
+		if err := r.Save(); err != nil {
+			t.Errorf("execxtest: %v", err)
+		}
+	})
+	return r
+}
+
+// Run implements execx.Executor
+func (r *Recorder) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	_, err := r.Capture(ctx, command, !streamToLog, args...)
+	return err
+}
+
+// Capture implements execx.Capturer
+func (r *Recorder) Capture(ctx context.Context, command string, tee bool, args ...string) (*execx.Result, error) {
+	res, err := r.real.Capture(ctx, command, tee, args...)
+	if res == nil {
+		return res, err
+	}
+
+	opts := execx.OptionsFrom(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record.Calls = append(r.record.Calls, TranscriptCall{
+		Command:  command,
+		Args:     args,
+		Dir:      opts.Dir,
+		Env:      execx.MergeEnv(opts.BaseEnv, opts.Env),
+		Stdout:   string(res.Stdout),
+		Stderr:   string(res.Stderr),
+		ExitCode: res.ExitCode,
+	})
+	return res, err
+}
+
+// Save writes the recorded transcript to the golden file
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// Replay creates a Fake that expects exactly the calls recorded in the golden file, in order
+func Replay(t testing.TB, path string) *Fake {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("execxtest: failed to read transcript: %v (set %s=1 to record it)", err, RecordEnv)
+	}
+
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		t.Fatalf("execxtest: failed to decode transcript %s: %v", path, err)
+	}
+
+	f := New(t).InOrder()
+	for _, c := range transcript.Calls {
+		f.Expect(c.Command, c.Args...).
+			Dir(c.Dir).
+			Env(c.Env...).
+			Stdout(c.Stdout).
+			Stderr(c.Stderr).
+			ExitCode(c.ExitCode)
+	}
+	return f
+}
+
+// Golden returns an executor backed by the golden transcript at path.
+// When RecordEnv is set, commands are executed with real and the transcript
+// is rewritten; otherwise the transcript is replayed.
+func Golden(t testing.TB, path string, real execx.Capturer) interface {
+	execx.Executor
+	execx.Capturer
+} {
+	t.Helper()
+	if os.Getenv(RecordEnv) != "" {
+		return Record(t, path, real)
+	}
+	return Replay(t, path)
+}
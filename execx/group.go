@@ -0,0 +1,194 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GroupMode controls how a Group reacts to a failing command
+type GroupMode int
+
+const (
+	// CollectAll runs every command and reports all failures
+	CollectAll GroupMode = iota
+	// FailFast cancels the remaining commands after the first failure
+	FailFast
+)
+
+// GroupStatus is the outcome of one command in a Group
+type GroupStatus string
+
+const (
+	StatusSucceeded GroupStatus = "succeeded"
+	StatusFailed    GroupStatus = "failed"
+	StatusCanceled  GroupStatus = "canceled"
+	StatusSkipped   GroupStatus = "skipped"
+)
+
+// GroupEntry reports the outcome of one command in a Group
+type GroupEntry struct {
+	Label    string
+	Command  string // Empty for functions added with Go
+	Args     []string
+	Status   GroupStatus
+	Duration time.Duration
+	Err      error
+}
+
+// GroupResult lists the outcome of every command in a Group, in the order they were added
+type GroupResult struct {
+	Entries []GroupEntry
+}
+
+// Failed returns the entries that did not succeed
+func (r *GroupResult) Failed() []GroupEntry {
+	var failed []GroupEntry
+	for _, e := range r.Entries {
+		if e.Status != StatusSucceeded {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+// String renders a one-line-per-command summary
+func (r *GroupResult) String() string {
+	width := 0
+	for _, e := range r.Entries {
+		width = max(width, len(e.Label))
+	}
+	var b strings.Builder
+	for _, e := range r.Entries {
+		fmt.Fprintf(&b, "%-*s  %-9s  %s\n", width, e.Label, e.Status, e.Duration.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+// groupTask is a unit of work queued in a Group
+type groupTask struct {
+	label   string
+	command string
+	args    []string
+	fn      func(ctx context.Context) error
+}
+
+// Group runs commands concurrently under a concurrency limit.
+// Each command's output lines are prefixed with its label.
+type Group struct {
+	executor Executor
+	limit    int
+	mode     GroupMode
+	tasks    []groupTask
+}
+
+// NewGroup creates a Group running at most limit commands at once.
+// A limit of zero or less uses the number of CPUs.
+func NewGroup(executor Executor, limit int, mode GroupMode) *Group {
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+	return &Group{
+		executor: executor,
+		limit:    limit,
+		mode:     mode,
+	}
+}
+
+// Add queues a command; its output is prefixed with label
+func (g *Group) Add(label, command string, args ...string) {
+	g.tasks = append(g.tasks, groupTask{label: label, command: command, args: args})
+}
+
+// Go queues a function. The context it receives carries the label and
+// output prefix, so commands it runs through execx are prefixed too.
+func (g *Group) Go(label string, fn func(ctx context.Context) error) {
+	g.tasks = append(g.tasks, groupTask{label: label, fn: fn})
+}
+
+// Run executes every queued task and waits for all of them.
+// The returned error joins the errors of all failed tasks.
+func (g *Group) Run(ctx context.Context) (*GroupResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	width := 0
+	for _, t := range g.tasks {
+		width = max(width, len(t.label))
+	}
+
+	result := &GroupResult{Entries: make([]GroupEntry, len(g.tasks))}
+	sem := make(chan struct{}, g.limit)
+	var wg sync.WaitGroup
+
+	for i, t := range g.tasks {
+		entry := &result.Entries[i]
+		entry.Label = t.label
+		entry.Command = t.command
+		entry.Args = t.args
+		entry.Status = StatusSkipped
+
+		// Acquire a slot before starting so tasks start in the order they were added
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		if ctx.Err() != nil {
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			taskCtx := WithOptions(ctx, Label(t.label), OutputPrefix(FormatPrefix(t.label, i, width)))
+			start := time.Now()
+			err := g.runTask(taskCtx, t)
+			entry.Duration = time.Since(start)
+
+			switch {
+			case err == nil:
+				entry.Status = StatusSucceeded
+			case errors.Is(err, context.Canceled) || (errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil):
+				// Stopped by the group or its parent; a task that failed on
+				// its own after a sibling canceled the group still failed
+				entry.Status = StatusCanceled
+				entry.Err = err
+			default:
+				entry.Status = StatusFailed
+				entry.Err = err
+				if g.mode == FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, e := range result.Entries {
+		if e.Status == StatusFailed {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Label, e.Err))
+		}
+	}
+	if len(errs) == 0 && len(result.Failed()) > 0 {
+		// Nothing failed on its own, the parent context stopped the group
+		errs = append(errs, ctx.Err())
+	}
+	return result, errors.Join(errs...)
+}
+
+// runTask executes a single queued task
+func (g *Group) runTask(ctx context.Context, t groupTask) error {
+	if t.fn != nil {
+		return t.fn(ctx)
+	}
+	return g.executor.Run(ctx, t.command, false, t.args...)
+}
@@ -0,0 +1,124 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// statuses lists the status of every entry in r
+func statuses(r *GroupResult) []GroupStatus {
+	var s []GroupStatus
+	for _, e := range r.Entries {
+		s = append(s, e.Status)
+	}
+	return s
+}
+
+func TestGroupCollectAll(t *testing.T) {
+	g := NewGroup(NewDryRunWithWriter(&strings.Builder{}), 2, CollectAll)
+	g.Go("a", func(ctx context.Context) error { return errors.New("boom") })
+	g.Go("b", func(ctx context.Context) error { return nil })
+	g.Go("c", func(ctx context.Context) error { return errors.New("bang") })
+
+	res, err := g.Run(context.Background())
+	want := []GroupStatus{StatusFailed, StatusSucceeded, StatusFailed}
+	if got := statuses(res); !slices.Equal(got, want) {
+		t.Errorf("statuses = %q, want %q", got, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "a: boom") || !strings.Contains(err.Error(), "c: bang") {
+		t.Errorf("Run() error = %v, want both failures", err)
+	}
+	if len(res.Failed()) != 2 {
+		t.Errorf("Failed() = %+v, want 2 entries", res.Failed())
+	}
+}
+
+func TestGroupFailFast(t *testing.T) {
+	g := NewGroup(NewDryRunWithWriter(&strings.Builder{}), 2, FailFast)
+	started := make(chan struct{})
+	g.Go("slow", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go("broken", func(ctx context.Context) error {
+		<-started
+		return errors.New("boom")
+	})
+	g.Go("queued", func(ctx context.Context) error { return nil })
+
+	res, err := g.Run(context.Background())
+	want := []GroupStatus{StatusCanceled, StatusFailed, StatusSkipped}
+	if got := statuses(res); !slices.Equal(got, want) {
+		t.Errorf("statuses = %q, want %q", got, want)
+	}
+	if err == nil || err.Error() != "broken: boom" {
+		t.Errorf("Run() error = %v, want only the failure", err)
+	}
+}
+
+func TestGroupParentCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewGroup(NewDryRunWithWriter(&strings.Builder{}), 1, CollectAll)
+	g.Go("a", func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	g.Go("b", func(ctx context.Context) error { return nil })
+
+	res, err := g.Run(ctx)
+	want := []GroupStatus{StatusCanceled, StatusSkipped}
+	if got := statuses(res); !slices.Equal(got, want) {
+		t.Errorf("statuses = %q, want %q", got, want)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGroupLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	g := NewGroup(NewDryRunWithWriter(&strings.Builder{}), 2, CollectAll)
+	for _, label := range []string{"a", "b", "c", "d", "e"} {
+		g.Go(label, func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			if got := OptionsFrom(ctx).Label; got != label {
+				t.Errorf("task %s ran with label %q", label, got)
+			}
+			return nil
+		})
+	}
+	if _, err := g.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d tasks ran at once, want at most 2", peak.Load())
+	}
+}
+
+func TestGroupCommands(t *testing.T) {
+	var out strings.Builder
+	dry := NewDryRunWithWriter(&out).Respond(&Result{Command: "helm", Args: []string{"lint"}, ExitCode: 1})
+	g := NewGroup(dry, 1, CollectAll)
+	g.Add("lint", "helm", "lint", "charts/app")
+	g.Add("list", "helm", "list")
+
+	res, err := g.Run(context.Background())
+	if exitCode(err) != 1 || res.Entries[0].Command != "helm" || !slices.Equal(res.Entries[0].Args, []string{"lint", "charts/app"}) {
+		t.Errorf("Run() = %+v, %v, want helm lint to fail with exit code 1", res.Entries[0], err)
+	}
+	if !strings.Contains(out.String(), "helm list") {
+		t.Errorf("dry run printed %q, want the second command too", out.String())
+	}
+}
@@ -0,0 +1,129 @@
+package execx
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// HermeticEnv is the environment variable that runs every command with a
+// hermetic environment, e.g. EXECX_HERMETIC=1, see Hermetic
+const HermeticEnv = "EXECX_HERMETIC"
+
+// HermeticAllowlist names the variables a hermetic command still inherits:
+// the ones locating the user, temporary files, the locale, proxies and
+// certificates, and the ones Windows needs to start a process at all.
+// A name ending in * matches every variable with that prefix.
+var HermeticAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "PWD", "TMPDIR", "TERM", "TZ", "LANG", "LC_*", "XDG_*",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERNAME", "USERPROFILE",
+	"APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES", "PROCESSOR_ARCHITECTURE", "NUMBER_OF_PROCESSORS",
+}
+
+// HermeticEnabled reports whether HermeticEnv asks for hermetic commands
+func HermeticEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(HermeticEnv))
+	return enabled
+}
+
+// Hermetic starts the command with only the variables in HermeticAllowlist,
+// those declared with PassEnv and those set with Env or BaseEnv, instead of everything
+// we inherited. Builds then no longer depend on e.g. GOFLAGS or HELM_DEBUG
+// exported in a developer's shell.
+func Hermetic() Option {
+	return func(o *Options) {
+		o.Hermetic = true
+	}
+}
+
+// PassEnv declares variables a hermetic command legitimately needs from our
+// environment, e.g. "KUBECONFIG" or "KO_*". It has no effect otherwise.
+func PassEnv(names ...string) Option {
+	return func(o *Options) {
+		for _, name := range names {
+			if !slices.Contains(o.PassEnv, name) {
+				o.PassEnv = append(o.PassEnv, name)
+			}
+		}
+	}
+}
+
+// hermetic reports whether commands run with o get a hermetic environment
+func (o Options) hermetic() bool {
+	return o.Hermetic || HermeticEnabled()
+}
+
+// hermeticEnviron returns the variables of environ a hermetic command run with
+// opts inherits, and logs the names of the others the first time that set is dropped
+func hermeticEnviron(ctx context.Context, environ []string, command string, opts Options) []string {
+	var kept, dropped []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if envAllowed(name, HermeticAllowlist) || envAllowed(name, opts.PassEnv) {
+			kept = append(kept, kv)
+		} else if !slices.ContainsFunc(opts.Env, hasKey(name)) && !slices.ContainsFunc(opts.BaseEnv, hasKey(name)) {
+			// A variable set with Env or BaseEnv is replaced rather than dropped
+			dropped = append(dropped, name)
+		}
+	}
+	slices.Sort(dropped)
+	if len(dropped) > 0 && firstDropped(dropped) {
+		logx.FromContext(ctx).InfoContext(ctx, "🧹 Dropped variables from a hermetic environment",
+			"command", command,
+			"dropped", dropped,
+		)
+	}
+	return kept
+}
+
+// envAllowed reports whether name matches one of patterns
+func envAllowed(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		switch {
+		case wildcard && hasPrefixFold(name, prefix):
+			return true
+		case !wildcard && envNameEqual(name, pattern):
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey returns a function reporting whether a KEY=VALUE pair sets name
+func hasKey(name string) func(string) bool {
+	return func(kv string) bool {
+		key, _, _ := strings.Cut(kv, "=")
+		return envNameEqual(key, name)
+	}
+}
+
+// envNameEqual compares variable names, ignoring case on Windows like the OS does
+func envNameEqual(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// hasPrefixFold is strings.HasPrefix, ignoring case on Windows
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && envNameEqual(s[:len(prefix)], prefix)
+}
+
+var droppedSeen sync.Map
+
+// firstDropped reports whether this sorted set of dropped names is new to the process,
+// so a long run logs it once rather than for every command
+func firstDropped(names []string) bool {
+	_, seen := droppedSeen.LoadOrStore(strings.Join(names, ","), true)
+	return !seen
+}
@@ -0,0 +1,93 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+func TestEnvAllowed(t *testing.T) {
+	patterns := []string{"PATH", "LC_*", "KO_*"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"PATH", true},
+		{"PATHS", false},
+		{"LC_ALL", true},
+		{"KO_DOCKER_REPO", true},
+		{"KOPS", false},
+		{"GOFLAGS", false},
+	}
+	for _, tt := range tests {
+		if got := envAllowed(tt.name, patterns); got != tt.want {
+			t.Errorf("envAllowed(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHermeticEnviron(t *testing.T) {
+	droppedSeen.Clear()
+	var logs bytes.Buffer
+	ctx := logx.NewContext(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+	environ := []string{"PATH=/bin", "HOME=/home/dev", "GOFLAGS=-mod=vendor", "HELM_DEBUG=1", "KUBECONFIG=/kc", "PROFILE=shell", "AWS_SECRET_ACCESS_KEY=x"}
+	opts := Options{
+		PassEnv: []string{"KUBECONFIG"},
+		Env:     []string{"HELM_DEBUG=0"},
+		BaseEnv: []string{"PROFILE=dev"},
+	}
+
+	got := hermeticEnviron(ctx, environ, "helm", opts)
+	if want := []string{"PATH=/bin", "HOME=/home/dev", "KUBECONFIG=/kc"}; !slices.Equal(got, want) {
+		t.Errorf("hermeticEnviron() = %q, want %q", got, want)
+	}
+	if !strings.Contains(logs.String(), `dropped="[AWS_SECRET_ACCESS_KEY GOFLAGS]"`) {
+		t.Errorf("logged %q, want the dropped names only", logs.String())
+	}
+
+	logs.Reset()
+	_ = hermeticEnviron(ctx, environ, "helm", opts)
+	if logs.Len() != 0 {
+		t.Errorf("logged the same dropped variables again: %q", logs.String())
+	}
+}
+
+func TestHermeticCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	t.Setenv("KUBECONFIG", "/kc")
+	t.Setenv("PROFILE", "shell")
+	script := `echo "$GOFLAGS|$KUBECONFIG|$PROFILE|$A"`
+	tests := []struct {
+		name     string
+		hermetic bool
+		env      string
+		want     string
+	}{
+		{"inherited", false, "", "-mod=vendor|/kc|dev|1\n"},
+		{"option", true, "", "|/kc|dev|1\n"},
+		{"environment variable", false, "1", "|/kc|dev|1\n"},
+	}
+	for _, tt := range tests {
+		t.Setenv(HermeticEnv, tt.env)
+		opts := []Option{PassEnv("KUBECONFIG"), BaseEnv("PROFILE=dev"), Env("A=1")}
+		if tt.hermetic {
+			opts = append(opts, Hermetic())
+		}
+		res, err := NewExec().Capture(WithOptions(context.Background(), opts...), "sh", false, "-c", script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(res.Stdout); got != tt.want {
+			t.Errorf("%s: command saw %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package execx
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+// Options holds per-invocation settings applied when a command is executed
+type Options struct {
+	Dir          string        // Working directory; empty means the current directory
+	Env          []string      // KEY=VALUE pairs added to or replacing the inherited environment
+	BaseEnv      []string      // KEY=VALUE pairs inherited beneath Env, but not part of a cache key, see BaseEnv
+	Stdin        iox.Reader    // Standard input; nil means StdinData, or the one StdinPolicy gives
+	StdinData    []byte        // Standard input read afresh by every command; used when Stdin is nil
+	Stdout       iox.Writer    // Additional writer receiving a copy of stdout
+	Stderr       iox.Writer    // Additional writer receiving a copy of stderr
+	Label        string        // Display label used in log output
+	OutputPrefix string        // Written before every line of terminal output
+	Redactor     *Redactor     // Masks secrets in logged commands and output
+	Timeout      time.Duration // Maximum run time; zero means no limit
+	GracePeriod  time.Duration // Time between SIGTERM and SIGKILL on cancellation; DefaultGracePeriod when zero
+
+	StderrTailLines int  // Stderr lines kept for CommandError; DefaultStderrTailLines when zero, none when negative
+	OrderedOutput   bool // Serialize stdout and stderr lines in arrival order with timestamps
+	PTY             bool // Run under a pseudo-terminal when our stdout is one, see PTY
+
+	Cache *CacheSpec // Inputs and outputs letting a CacheInterceptor skip the command, see Cached
+
+	StdinPolicy StdinPolicy // What the command reads when Stdin is nil; the executor's policy when zero
+
+	Hermetic bool     // Inherit only HermeticAllowlist and PassEnv, see Hermetic
+	PassEnv  []string // Variables, or prefixes ending in *, a hermetic command inherits too
+}
+
+// DefaultGracePeriod is how long a canceled command may take to exit before it is killed
+const DefaultGracePeriod = 10 * time.Second
+
+// Option configures an invocation
+type Option func(*Options)
+
+// Dir sets the working directory for the command
+func Dir(dir string) Option {
+	return func(o *Options) {
+		o.Dir = dir
+	}
+}
+
+// Env adds or replaces environment variables given as KEY=VALUE pairs
+func Env(env ...string) Option {
+	return func(o *Options) {
+		o.Env = MergeEnv(o.Env, env)
+	}
+}
+
+// BaseEnv adds or replaces variables the command inherits as if they were set
+// in our own environment, e.g. the variables of a profile. They are shown in
+// dry-run and reproduce command lines like those set with Env, but unlike
+// them they are not hashed into cache keys, and a hermetic command still
+// inherits them.
+func BaseEnv(env ...string) Option {
+	return func(o *Options) {
+		o.BaseEnv = MergeEnv(o.BaseEnv, env)
+	}
+}
+
+// Stdin supplies the standard input for the command from a reader.
+// The reader is consumed, so it only serves one command.
+func Stdin(r iox.Reader) Option {
+	return func(o *Options) {
+		o.Stdin = r
+		o.StdinData = nil
+	}
+}
+
+// StdinBytes supplies the standard input for the command from a byte slice.
+// Every command run with the options reads all of b.
+func StdinBytes(b []byte) Option {
+	return func(o *Options) {
+		o.Stdin = nil
+		o.StdinData = append([]byte{}, b...)
+	}
+}
+
+// StdinFrom sets what the command reads when no Stdin is given, e.g. NullStdin()
+// for a tool that must never prompt
+func StdinFrom(p StdinPolicy) Option {
+	return func(o *Options) {
+		o.StdinPolicy = p
+	}
+}
+
+// OutputPrefix writes prefix before every line the command prints to the terminal
+func OutputPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.OutputPrefix = prefix
+	}
+}
+
+// TeeStdout copies the command's stdout to w in addition to its normal destination.
+// Writers from repeated calls all receive the output.
+func TeeStdout(w iox.Writer) Option {
+	return func(o *Options) {
+		o.Stdout = teeWriter(o.Stdout, w)
+	}
+}
+
+// TeeStderr copies the command's stderr to w in addition to its normal destination.
+// Writers from repeated calls all receive the output.
+func TeeStderr(w iox.Writer) Option {
+	return func(o *Options) {
+		o.Stderr = teeWriter(o.Stderr, w)
+	}
+}
+
+// teeWriter adds w to an existing tee destination
+func teeWriter(existing, w iox.Writer) iox.Writer {
+	if existing == nil {
+		return w
+	}
+	return io.MultiWriter(existing, w)
+}
+
+// Label sets the display label used in log output
+func Label(label string) Option {
+	return func(o *Options) {
+		o.Label = label
+	}
+}
+
+// Timeout limits how long the command may run before it is canceled
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// GracePeriod sets how long a canceled command may take to exit after SIGTERM before it is killed
+func GracePeriod(d time.Duration) Option {
+	return func(o *Options) {
+		o.GracePeriod = d
+	}
+}
+
+// StderrTail sets how many trailing stderr lines a CommandError keeps; negative disables it
+func StderrTail(lines int) Option {
+	return func(o *Options) {
+		o.StderrTailLines = lines
+	}
+}
+
+// OrderedOutput keeps the relative order of stdout and stderr lines.
+// Lines are written one at a time in the order they are read, terminal lines
+// are preceded by a timestamp, and Result.Lines records them all.
+func OrderedOutput() Option {
+	return func(o *Options) {
+		o.OrderedOutput = true
+	}
+}
+
+// PTY runs the command under a pseudo-terminal so tools keep colors and
+// progress output. It only applies on Linux when our own stdout is a terminal
+// and the output is shown there; pipes are used otherwise, e.g. in CI.
+// Under a PTY stdout and stderr are merged into stdout, and captured output,
+// tees and the stderr tail receive a copy stripped of ANSI escape sequences.
+func PTY() Option {
+	return func(o *Options) {
+		o.PTY = true
+	}
+}
+
+// RedactWith masks the secrets known to r wherever the command is displayed
+func RedactWith(r *Redactor) Option {
+	return func(o *Options) {
+		o.Redactor = r
+	}
+}
+
+// DefaultRedaction makes DefaultRedactor mask secrets unless a Redactor is set
+func DefaultRedaction() Option {
+	return func(o *Options) {
+		if o.Redactor == nil {
+			o.Redactor = defaultRedactor
+		}
+	}
+}
+
+// With returns a copy of o with opts applied
+func (o Options) With(opts ...Option) Options {
+	o.Env = append([]string(nil), o.Env...)
+	o.BaseEnv = append([]string(nil), o.BaseEnv...)
+	o.PassEnv = append([]string(nil), o.PassEnv...)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type optionsKey struct{}
+
+// stderrTailLines returns how many stderr lines to keep for a CommandError
+func (o Options) stderrTailLines() int {
+	if o.StderrTailLines == 0 {
+		return DefaultStderrTailLines
+	}
+	return o.StderrTailLines
+}
+
+// WithOptions returns a copy of ctx carrying the given options.
+// Options already present in ctx are kept unless overridden.
+func WithOptions(ctx context.Context, opts ...Option) context.Context {
+	return contextWithOptions(ctx, OptionsFrom(ctx).With(opts...))
+}
+
+// contextWithOptions returns a copy of ctx carrying exactly o
+func contextWithOptions(ctx context.Context, o Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, o)
+}
+
+// OptionsFrom returns the options carried by ctx
+func OptionsFrom(ctx context.Context) Options {
+	o, _ := ctx.Value(optionsKey{}).(Options)
+	// Never hand out the stored slice, callers may append to it
+	o.Env = append([]string(nil), o.Env...)
+	o.BaseEnv = append([]string(nil), o.BaseEnv...)
+	o.PassEnv = append([]string(nil), o.PassEnv...)
+	return o
+}
+
+// environment returns the variables set for the command, Env overriding BaseEnv
+func (o Options) environment() []string {
+	return MergeEnv(o.BaseEnv, o.Env)
+}
+
+// MergeEnv returns base with overrides applied.
+// Entries in overrides replace entries in base that share the same key.
+func MergeEnv(base, overrides []string) []string {
+	merged := make([]string, 0, len(base)+len(overrides))
+	index := make(map[string]int, len(base)+len(overrides))
+	for _, kv := range append(append([]string(nil), base...), overrides...) {
+		key, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[key]; ok {
+			merged[i] = kv
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, kv)
+	}
+	return merged
+}
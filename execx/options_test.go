@@ -0,0 +1,48 @@
+package execx
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	tests := []struct {
+		name            string
+		base, overrides []string
+		want            []string
+	}{
+		{"both empty", nil, nil, []string{}},
+		{"only base", []string{"A=1", "B=2"}, nil, []string{"A=1", "B=2"}},
+		{"only overrides", nil, []string{"A=1"}, []string{"A=1"}},
+		{"replaces in place", []string{"A=1", "B=2", "C=3"}, []string{"B=x"}, []string{"A=1", "B=x", "C=3"}},
+		{"appends new keys", []string{"A=1"}, []string{"B=2"}, []string{"A=1", "B=2"}},
+		{"last override wins", []string{"A=1"}, []string{"A=2", "A=3"}, []string{"A=3"}},
+		{"duplicate in base", []string{"A=1", "A=2"}, nil, []string{"A=2"}},
+		{"empty value", []string{"A=1"}, []string{"A="}, []string{"A="}},
+		{"value with equals", []string{"A=1"}, []string{"A=b=c"}, []string{"A=b=c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := slices.Clone(tt.base)
+			got := MergeEnv(tt.base, tt.overrides)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MergeEnv(%q, %q) = %q, want %q", tt.base, tt.overrides, got, tt.want)
+			}
+			if !slices.Equal(tt.base, base) {
+				t.Errorf("MergeEnv modified base: %q", tt.base)
+			}
+		})
+	}
+}
+
+func TestOptionsFromCopiesSlices(t *testing.T) {
+	ctx := WithOptions(context.Background(), Env("A=1"), BaseEnv("B=1"), PassEnv("C"))
+	o := OptionsFrom(ctx)
+	o.Env[0], o.BaseEnv[0], o.PassEnv[0] = "A=2", "B=2", "D"
+
+	o = OptionsFrom(ctx)
+	if o.Env[0] != "A=1" || o.BaseEnv[0] != "B=1" || o.PassEnv[0] != "C" {
+		t.Errorf("options in ctx were modified: %+v", o)
+	}
+}
@@ -0,0 +1,243 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// Stream names used in Line
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// Line is one line of output recorded when OrderedOutput is enabled
+type Line struct {
+	Time   time.Time // When the line was read
+	Stream string    // StreamStdout or StreamStderr
+	Text   string    // Line content without the trailing newline
+}
+
+// maxLineLength bounds buffered partial lines; longer lines are split
+const maxLineLength = 1024 * 1024
+
+// lineWriter calls emit for every complete line written to it.
+// Call Flush after the last write to emit an unterminated last line.
+type lineWriter struct {
+	emit    func(line []byte) error
+	partial bytes.Buffer
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial.Write(p)
+	for {
+		data := w.partial.Bytes()
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if len(data) >= maxLineLength {
+				return len(p), w.emit(w.partial.Next(len(data)))
+			}
+			return len(p), nil
+		}
+		line := w.partial.Next(i + 1)
+		if err := w.emit(bytes.TrimSuffix(line[:i], []byte("\r"))); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush emits a trailing unterminated line
+func (w *lineWriter) Flush() error {
+	if w.partial.Len() == 0 {
+		return nil
+	}
+	return w.emit(w.partial.Next(w.partial.Len()))
+}
+
+// drainWriter forwards writes until the first error and discards the rest.
+// It never fails, so a broken destination cannot stall the command.
+type drainWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Write implements io.Writer
+func (d *drainWriter) Write(p []byte) (int, error) {
+	if d.err == nil {
+		_, d.err = d.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// outputs holds the destinations of a command's stdout and stderr
+type outputs struct {
+	stdout    io.Writer
+	stderr    io.Writer
+	stdoutBuf bytes.Buffer
+	stderrBuf bytes.Buffer
+
+	pty      *ptySession
+	flushers []*lineWriter
+	mu       sync.Mutex // serializes ordered lines
+	lines    []Line
+}
+
+// newOutputs builds the writers for inv according to its output mode.
+// stderrTail receives a copy of stderr for error reporting. When pty is set,
+// the command writes both streams to it and everything is treated as stdout.
+func newOutputs(ctx context.Context, inv *Invocation, stderrTail io.Writer, pty *ptySession) *outputs {
+	o := &outputs{pty: pty}
+	if inv.pipe != nil {
+		// Connected directly, so the stage gets SIGPIPE once its reader exits
+		o.stdout = inv.pipe
+		o.stderr = o.stream(ctx, inv, StreamStderr, os.Stderr, &o.stderrBuf, slog.LevelError, teeWriter(inv.Options.Stderr, stderrTail))
+		return o
+	}
+	if pty != nil {
+		o.stdout = o.stream(ctx, inv, StreamStdout, os.Stdout, &o.stdoutBuf, slog.LevelInfo, teeWriter(inv.Options.Stdout, stderrTail))
+		return o
+	}
+	o.stdout = o.stream(ctx, inv, StreamStdout, os.Stdout, &o.stdoutBuf, slog.LevelInfo, inv.Options.Stdout)
+	o.stderr = o.stream(ctx, inv, StreamStderr, os.Stderr, &o.stderrBuf, slog.LevelError, teeWriter(inv.Options.Stderr, stderrTail))
+	return o
+}
+
+// connect attaches the writers, or the pseudo-terminal, to cmd
+func (o *outputs) connect(cmd Commander) {
+	if o.pty != nil {
+		o.pty.connect(cmd)
+		return
+	}
+	cmd.SetStdout(o.stdout)
+	cmd.SetStderr(o.stderr)
+}
+
+// started begins copying from the pseudo-terminal once cmd has started
+func (o *outputs) started() {
+	if o.pty != nil {
+		o.pty.start(o.stdout)
+	}
+}
+
+// stream builds the writer for one output stream
+func (o *outputs) stream(ctx context.Context, inv *Invocation, name string, terminal io.Writer, buf *bytes.Buffer, level slog.Level, tee io.Writer) io.Writer {
+	opts := inv.Options
+
+	// display is where the output is shown, nil when it is only captured
+	var display io.Writer
+	switch {
+	case inv.Capture && !inv.Tee:
+	case inv.StreamToLog && !inv.Capture:
+		display = o.track(newLogWriter(ctx, level, opts.Label))
+	case opts.OutputPrefix != "":
+		display = o.track(newPrefixWriter(terminal, opts.OutputPrefix))
+	default:
+		display = terminal
+	}
+	if opts.OrderedOutput {
+		// slog lines carry their own timestamp
+		stamp := display != nil && !(inv.StreamToLog && !inv.Capture)
+		display = o.track(o.orderedWriter(name, display, stamp))
+	}
+	if opts.Redactor != nil {
+		// Secrets are masked line by line, so they cannot be split across writes
+		if display != nil {
+			display = o.track(newRedactWriter(display, opts.Redactor))
+		}
+		if tee != nil {
+			tee = o.track(newRedactWriter(tee, opts.Redactor))
+		}
+	}
+
+	var capture io.Writer = buf
+	if o.pty != nil {
+		// Terminal output is shown as is, everything else gets plain text
+		capture = o.track(plainTextWriter(buf))
+		if tee != nil {
+			tee = o.track(plainTextWriter(tee))
+		}
+	}
+
+	var writers []io.Writer
+	if inv.Capture {
+		writers = append(writers, capture)
+	}
+	if display != nil {
+		writers = append(writers, &drainWriter{w: display})
+	}
+	if tee != nil {
+		writers = append(writers, &drainWriter{w: tee})
+	}
+	if len(writers) == 0 {
+		return io.Discard
+	}
+	return io.MultiWriter(writers...)
+}
+
+// track registers a lineWriter to be flushed once the command has finished
+func (o *outputs) track(w *lineWriter) *lineWriter {
+	o.flushers = append(o.flushers, w)
+	return w
+}
+
+// flush waits for the pseudo-terminal to drain, then emits pending
+// partial lines, outermost writers first
+func (o *outputs) flush() {
+	if o.pty != nil {
+		o.pty.close()
+	}
+	for i := len(o.flushers) - 1; i >= 0; i-- {
+		_ = o.flushers[i].Flush()
+	}
+}
+
+// orderedWriter records lines of one stream in arrival order and forwards
+// them to display, optionally preceded by their timestamp
+func (o *outputs) orderedWriter(stream string, display io.Writer, stamp bool) *lineWriter {
+	return &lineWriter{emit: func(line []byte) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		now := time.Now()
+		o.lines = append(o.lines, Line{Time: now, Stream: stream, Text: string(line)})
+		if display == nil {
+			return nil
+		}
+		var out []byte
+		if stamp {
+			out = append(out, now.Format("15:04:05.000 ")...)
+		}
+		out = append(append(out, line...), '\n')
+		_, err := display.Write(out)
+		return err
+	}}
+}
+
+// newRedactWriter creates a writer that masks the secrets known to r in every line
+func newRedactWriter(out io.Writer, r *Redactor) *lineWriter {
+	return &lineWriter{emit: func(line []byte) error {
+		_, err := io.WriteString(out, r.Redact(string(line))+"\n")
+		return err
+	}}
+}
+
+// newLogWriter creates a writer logging every line to slog with the given level.
+// A non-empty label is attached to every line as the "label" attribute.
+func newLogWriter(ctx context.Context, level slog.Level, label string) *lineWriter {
+	return &lineWriter{emit: func(line []byte) error {
+		if label != "" {
+			logx.FromContext(ctx).Log(ctx, level, string(line), "label", label)
+		} else {
+			logx.FromContext(ctx).Log(ctx, level, string(line))
+		}
+		return nil
+	}}
+}
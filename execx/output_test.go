@@ -0,0 +1,76 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{emit: func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}}
+	for _, chunk := range []string{"fir", "st\nsec", "ond\r\n", "\nlast"} {
+		_, _ = w.Write([]byte(chunk))
+	}
+	if want := []string{"first", "second", ""}; !slices.Equal(lines, want) {
+		t.Errorf("lines before Flush = %q, want %q", lines, want)
+	}
+	_ = w.Flush()
+	_ = w.Flush()
+	if want := []string{"first", "second", "", "last"}; !slices.Equal(lines, want) {
+		t.Errorf("lines after Flush = %q, want %q", lines, want)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newPrefixWriter(&out, "[api] ")
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	_ = w.Flush()
+	if want := "[api] one\n[api] two\n[api] three\n"; out.String() != want {
+		t.Errorf("prefixed output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestOrderedOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	script := `echo one; sleep 0.05; echo two >&2; sleep 0.05; printf three`
+	ctx := WithOptions(context.Background(), OrderedOutput())
+	res, err := NewExec().Capture(ctx, "sh", false, "-c", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range res.Lines {
+		got = append(got, l.Stream+":"+l.Text)
+	}
+	if want := []string{"stdout:one", "stderr:two", "stdout:three"}; !slices.Equal(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+	if string(res.Stdout) != "one\nthree" || string(res.Stderr) != "two\n" {
+		t.Errorf("captured stdout %q and stderr %q", res.Stdout, res.Stderr)
+	}
+}
+
+func TestRunFlushesPartialLines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	var stdout, stderr strings.Builder
+	ctx := WithOptions(context.Background(), TeeStdout(&stdout), TeeStderr(&stderr), RedactWith(NewRedactor("s3cr3t")))
+	if err := NewExec().Run(ctx, "sh", false, "-c", `printf 'token s3cr3t'; printf 'no newline' >&2`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "token ***\n" || stderr.String() != "no newline\n" {
+		t.Errorf("tees received %q and %q after Run returned, want the unterminated lines", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,174 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Stage is a single command of a Pipeline
+type Stage struct {
+	Command string
+	Args    []string
+}
+
+// Pipeline connects the stdout of each command to the stdin of the next,
+// like `helm template ... | kubeconform` in a shell with pipefail set.
+// Every stage is an invocation of the executor, so its interceptors see each
+// of them and a DryRun only prints them. Stages are connected by OS pipes when
+// the executor runs commands with an Exec.
+// Per-invocation options from the context apply to every stage; Stdin feeds
+// the first stage and the Stdout tee receives the output of the last one.
+type Pipeline struct {
+	executor Executor
+	stages   []Stage
+}
+
+// NewPipeline creates an empty Pipeline running its stages with executor
+func NewPipeline(executor Executor) *Pipeline {
+	return &Pipeline{executor: executor}
+}
+
+// NewDefaultPipeline creates an empty Pipeline using NewDefaultExecutor
+func NewDefaultPipeline() *Pipeline {
+	return NewPipeline(NewDefaultExecutor())
+}
+
+// Pipe appends a stage reading the output of the previous one
+func (p *Pipeline) Pipe(command string, args ...string) *Pipeline {
+	p.stages = append(p.stages, Stage{Command: command, Args: args})
+	return p
+}
+
+// PipelineResult holds the outcome of every stage of a finished pipeline
+type PipelineResult struct {
+	Stages []*Result // One result per stage, in pipeline order
+}
+
+// Last returns the result of the final stage, which holds the captured output
+func (r *PipelineResult) Last() *Result {
+	return r.Stages[len(r.Stages)-1]
+}
+
+// PipelineError reports the stage a pipeline failed at
+type PipelineError struct {
+	Stage  int // Zero-based index of the failed stage
+	Stages int // Number of stages in the pipeline
+	Err    error
+}
+
+// Error implements the error interface
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline stage %d of %d failed: %v", e.Stage+1, e.Stages, e.Err)
+}
+
+// Unwrap returns the error of the failed stage
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the pipeline, streaming the final output to the terminal
+func (p *Pipeline) Run(ctx context.Context) error {
+	_, err := p.run(ctx, false, true)
+	return err
+}
+
+// Capture executes the pipeline and captures the output of the final stage.
+// If tee is true, that output is also copied live to the terminal.
+func (p *Pipeline) Capture(ctx context.Context, tee bool) (*PipelineResult, error) {
+	return p.run(ctx, true, tee)
+}
+
+// run starts every stage, waits for all of them and reports the last failure.
+// A stage killed by SIGPIPE is not a failure when a later stage succeeded,
+// since it only means its reader stopped early, e.g. `... | head`.
+func (p *Pipeline) run(ctx context.Context, capture, tee bool) (*PipelineResult, error) {
+	if len(p.stages) == 0 {
+		return nil, errors.New("pipeline has no stages")
+	}
+
+	n := len(p.stages)
+	opts := OptionsFrom(ctx)
+	invs := make([]*Invocation, n)
+	for i, stage := range p.stages {
+		stageOpts := opts.With()
+		// A stage's output only exists while the pipeline runs
+		stageOpts.Cache = nil
+		if i < n-1 {
+			stageOpts.Stdout = nil
+		}
+		invs[i] = &Invocation{
+			Command: stage.Command,
+			Args:    stage.Args,
+			Capture: i == n-1 && capture,
+			Tee:     tee,
+			Options: stageOpts,
+		}
+	}
+
+	// Our copies of both ends are closed once the stage using them exited:
+	// its reader then sees EOF, and its writer gets SIGPIPE when writing on
+	readers := make([]*os.File, n)
+	for i := range n - 1 {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closePipes(invs, readers)
+			return nil, fmt.Errorf("failed to connect pipeline stage %d (%s): %w", i+1, invs[i].Command, err)
+		}
+		invs[i].pipe = w
+		readers[i+1] = r
+		invs[i+1].Options.Stdin = r
+		invs[i+1].Options.StdinData = nil
+	}
+
+	handler := HandlerFor(p.executor)
+	res := &PipelineResult{Stages: make([]*Result, n)}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, inv := range invs {
+		wg.Go(func() {
+			res.Stages[i], errs[i] = handler(ctx, inv)
+			if res.Stages[i] == nil {
+				res.Stages[i] = &Result{Command: inv.Command, Args: inv.Args, ExitCode: exitCode(errs[i])}
+			}
+			closePipes(invs[i:i+1], readers[i:i+1])
+		})
+	}
+	wg.Wait()
+
+	return res, pipelineError(errs)
+}
+
+// closePipes closes our copies of the pipe ends connecting invs
+func closePipes(invs []*Invocation, readers []*os.File) {
+	for i, inv := range invs {
+		if inv.pipe != nil {
+			_ = inv.pipe.Close()
+		}
+		if readers[i] != nil {
+			_ = readers[i].Close()
+		}
+	}
+}
+
+// pipelineError returns the failure of the last stage that failed for a reason
+// other than a broken pipe, like pipefail does, or nil when every stage succeeded
+func pipelineError(errs []error) error {
+	n := len(errs)
+	for i := n - 1; i >= 0; i-- {
+		if errs[i] == nil || (i < n-1 && brokenPipe(errs[i])) {
+			continue
+		}
+		return &PipelineError{Stage: i, Stages: n, Err: errs[i]}
+	}
+	return nil
+}
+
+// brokenPipe reports whether err is a command killed by SIGPIPE
+func brokenPipe(err error) bool {
+	var cmdErr *CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Signal == syscall.SIGPIPE.String()
+}
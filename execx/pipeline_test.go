@@ -0,0 +1,120 @@
+//go:build unix
+
+package execx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sigpipeError is the error of a stage killed by SIGPIPE
+var sigpipeError = &CommandError{Command: "yes", ExitCode: -1, Signal: "broken pipe", Err: errors.New("signal: broken pipe")}
+
+func TestBrokenPipe(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("broken pipe"), false},
+		{&CommandError{Command: "cat", ExitCode: 1, Err: errors.New("exit status 1")}, false},
+		{&CommandError{Command: "cat", ExitCode: -1, Signal: "killed", Err: errors.New("signal: killed")}, false},
+		{sigpipeError, true},
+		{&PipelineError{Stage: 0, Stages: 2, Err: sigpipeError}, true},
+	}
+	for _, tt := range tests {
+		if got := brokenPipe(tt.err); got != tt.want {
+			t.Errorf("brokenPipe(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	failed := errors.New("exit status 1")
+	tests := []struct {
+		name  string
+		errs  []error
+		stage int // -1 when the pipeline succeeded
+	}{
+		{"all succeed", []error{nil, nil, nil}, -1},
+		{"writer stopped by its reader", []error{sigpipeError, nil}, -1},
+		{"last stage killed by SIGPIPE", []error{nil, sigpipeError}, 1},
+		{"first failure", []error{failed, nil, nil}, 0},
+		{"last failure wins", []error{failed, sigpipeError, failed}, 2},
+		{"failure behind a broken pipe", []error{failed, sigpipeError, nil}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pipelineError(tt.errs)
+			var pipeErr *PipelineError
+			switch {
+			case tt.stage < 0 && err != nil:
+				t.Errorf("pipelineError() = %v, want nil", err)
+			case tt.stage >= 0 && (!errors.As(err, &pipeErr) || pipeErr.Stage != tt.stage):
+				t.Errorf("pipelineError() = %v, want stage %d to fail", err, tt.stage+1)
+			}
+		})
+	}
+}
+
+func TestPipelineCapture(t *testing.T) {
+	ctx := context.Background()
+	res, err := NewPipeline(NewExec()).
+		Pipe("printf", `a\nb\nc\n`).
+		Pipe("grep", "-v", "b").
+		Capture(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Last().Stdout); got != "a\nc\n" {
+		t.Errorf("Last().Stdout = %q, want %q", got, "a\nc\n")
+	}
+}
+
+func TestPipelineStopsWriterEarly(t *testing.T) {
+	res, err := NewPipeline(NewExec()).Pipe("yes").Pipe("head", "-n", "1").Capture(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Capture() error = %v, want the SIGPIPE of yes ignored", err)
+	}
+	if got := string(res.Last().Stdout); got != "y\n" {
+		t.Errorf("Last().Stdout = %q", got)
+	}
+}
+
+func TestPipelineFailedStage(t *testing.T) {
+	_, err := NewPipeline(NewExec()).Pipe("sh", "-c", "exit 3").Pipe("cat").Capture(context.Background(), false)
+	var pipeErr *PipelineError
+	if !errors.As(err, &pipeErr) || pipeErr.Stage != 0 || exitCode(err) != 3 {
+		t.Errorf("Capture() error = %v, want stage 1 to exit with 3", err)
+	}
+}
+
+func TestPipelineUsesExecutor(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	record := func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			mu.Lock()
+			seen = append(seen, inv.Command)
+			mu.Unlock()
+			return next(ctx, inv)
+		}
+	}
+	var out bytes.Buffer
+	executor := NewChain(NewDryRunWithWriter(&out), record)
+	if err := NewPipeline(executor).Pipe("helm", "template", ".").Pipe("kubeconform", "-").Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("interceptor saw %q, want both stages", seen)
+	}
+	for _, line := range []string{"helm template .  # dry-run", "kubeconform -  # dry-run"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("dry run printed %q, want %q", out.String(), line)
+		}
+	}
+}
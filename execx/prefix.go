@@ -0,0 +1,49 @@
+package execx
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+// terminalMu serializes prefixed lines so concurrent commands never interleave mid-line
+var terminalMu sync.Mutex
+
+// prefixColors are the ANSI colors cycled through for output prefixes
+var prefixColors = []int{36, 33, 35, 32, 34, 91, 96, 93}
+
+// FormatPrefix renders the output prefix for a label, padded to width.
+// The index selects the color; colors are only used on a terminal and
+// when NO_COLOR is unset.
+func FormatPrefix(label string, index, width int) string {
+	prefix := fmt.Sprintf("[%-*s] ", width, label)
+	if !colorEnabled() {
+		return prefix
+	}
+	color := prefixColors[index%len(prefixColors)]
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, prefix)
+}
+
+// colorEnabled reports whether ANSI colors should be written to stdout
+func colorEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(os.Stdout.Fd())
+}
+
+// newPrefixWriter creates a writer that writes every line to out preceded by prefix.
+// Each line is written in a single call so concurrent commands never interleave mid-line.
+func newPrefixWriter(out iox.Writer, prefix string) *lineWriter {
+	return &lineWriter{emit: func(line []byte) error {
+		buf := make([]byte, 0, len(prefix)+len(line)+1)
+		buf = append(append(append(buf, prefix...), line...), '\n')
+
+		terminalMu.Lock()
+		defer terminalMu.Unlock()
+		_, err := out.Write(buf)
+		return err
+	}}
+}
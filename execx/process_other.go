@@ -0,0 +1,16 @@
+//go:build !unix
+
+package execx
+
+import (
+	"os/exec"
+	"time"
+)
+
+// configureTermination bounds how long Wait blocks after ctx cancellation.
+// Process groups are not supported on this platform, so only the direct
+// child is killed.
+func configureTermination(cmd *exec.Cmd, grace time.Duration, interactive bool) *killTimer {
+	cmd.WaitDelay = grace
+	return &killTimer{}
+}
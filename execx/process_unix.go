@@ -0,0 +1,53 @@
+//go:build unix
+
+package execx
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureTermination makes ctx cancellation stop the command gracefully.
+// The command runs in its own process group so SIGTERM reaches every process
+// it spawned, followed by SIGKILL once grace has elapsed, even when the
+// command itself already exited.
+//
+// Commands attached to an interactive terminal stay in our process group, so
+// they keep receiving keyboard signals and can read from the terminal. Only
+// the command itself is signaled then: processes it left running in the
+// background are not stopped by a cancellation.
+// The returned killTimer must be told once the command has been waited for.
+func configureTermination(cmd *exec.Cmd, grace time.Duration, interactive bool) *killTimer {
+	kill := &killTimer{}
+	if !interactive {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		kill.lingering = func() bool {
+			return !errors.Is(syscall.Kill(-cmd.Process.Pid, 0), syscall.ESRCH)
+		}
+	}
+
+	cmd.Cancel = func() error {
+		// A negative pid addresses the whole process group
+		target := cmd.Process.Pid
+		if !interactive {
+			target = -target
+		}
+		kill.arm(grace, func() {
+			// ESRCH: everything already exited
+			_ = syscall.Kill(target, syscall.SIGKILL)
+		})
+		if err := syscall.Kill(target, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		return nil
+	}
+	// Safety net in case the process ignores both signals
+	cmd.WaitDelay = grace + time.Second
+	return kill
+}
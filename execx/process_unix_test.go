@@ -0,0 +1,83 @@
+//go:build unix
+
+package execx
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// spawnScript starts a shell that prints the pid of a background sleep
+// ignoring SIGTERM, then waits for it. The shell itself exits on SIGTERM.
+const spawnScript = `(trap "" TERM; exec sleep 30) & echo $!; wait`
+
+// startOrphaning starts spawnScript with the given termination setup and
+// returns the pid of the sleep and a reader reaching EOF once it exited
+func startOrphaning(t *testing.T, ctx context.Context, interactive bool) (*ExecCmd, int, io.Reader) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", spawnScript)
+	cmd.Stdout = w
+	kill := configureTermination(cmd, 100*time.Millisecond, interactive)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	t.Cleanup(func() { kill.stop() })
+
+	out := bufio.NewReader(r)
+	line, err := out.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ExecCmd{Cmd: cmd, kill: kill}, pid, out
+}
+
+func TestTerminationKillsGroupAfterLeaderExits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, _, out := startOrphaning(t, ctx, false)
+
+	cancel()
+	_ = cmd.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, out)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("background process survived the SIGKILL of the canceled command's group")
+	}
+}
+
+func TestTerminationInteractiveSignalsOnlyCommand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, pid, _ := startOrphaning(t, ctx, true)
+	defer func() { _ = syscall.Kill(pid, syscall.SIGKILL) }()
+
+	cancel()
+	_ = cmd.Wait()
+	time.Sleep(300 * time.Millisecond)
+	if err := syscall.Kill(pid, 0); err != nil {
+		t.Errorf("background process of an interactive command was signaled: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package execx
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// ptyDrainTimeout bounds how long output is read after the command exited,
+// in case a background process it spawned keeps the terminal open
+const ptyDrainTimeout = 2 * time.Second
+
+// ansiPattern matches ANSI escape sequences: CSI, OSC and two-byte escapes
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes ANSI escape sequences such as colors and cursor movement from s
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// ptySession is a pseudo-terminal the command writes its stdout and stderr to
+type ptySession struct {
+	master *os.File
+	slave  *os.File
+	done   chan struct{}
+	stop   func()
+}
+
+// newPTY allocates a pseudo-terminal for cmd when PTY mode is requested and its
+// output is shown on our own terminal. It returns nil when pipes must be used,
+// e.g. in CI, for fake commands or on platforms without PTY support.
+func newPTY(ctx context.Context, cmd Commander, inv *Invocation) *ptySession {
+	opts := inv.Options
+	shown := (!inv.Capture || inv.Tee) && !(inv.StreamToLog && !inv.Capture)
+	if !opts.PTY || !shown || inv.pipe != nil || !isTerminal(os.Stdout.Fd()) {
+		return nil
+	}
+	ec, ok := cmd.(*ExecCmd)
+	if !ok {
+		return nil
+	}
+
+	master, slave, err := openPTY()
+	if err != nil {
+		logx.FromContext(ctx).DebugContext(ctx, "PTY unavailable, using pipes", "command", inv.Command, "err", err)
+		return nil
+	}
+	grace := opts.GracePeriod
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	ec.kill = configurePTYSession(ec.Cmd, grace)
+	return &ptySession{master: master, slave: slave, done: make(chan struct{})}
+}
+
+// connect makes the terminal the command's stdout and stderr
+func (p *ptySession) connect(cmd Commander) {
+	cmd.SetStdout(p.slave)
+	cmd.SetStderr(p.slave)
+}
+
+// start copies terminal output to w and keeps the window size in sync.
+// It must be called once the command has started.
+func (p *ptySession) start(w io.Writer) {
+	// The child holds its own copy; ours would keep the terminal open forever
+	_ = p.slave.Close()
+	p.stop = watchWindowSize(p.master)
+	go func() {
+		defer close(p.done)
+		// Reading fails with EIO once the command closed the terminal
+		_, _ = io.Copy(w, p.master)
+	}()
+}
+
+// close waits for the remaining output and releases the terminal
+func (p *ptySession) close() {
+	_ = p.slave.Close()
+	if p.stop == nil {
+		_ = p.master.Close()
+		return
+	}
+	p.stop()
+	select {
+	case <-p.done:
+	case <-time.After(ptyDrainTimeout):
+	}
+	_ = p.master.Close()
+	<-p.done
+}
+
+// plainTextWriter creates a writer that removes ANSI escape sequences from every
+// line written to it, keeping only the text after the last carriage return the
+// way a terminal would show it
+func plainTextWriter(out io.Writer) *lineWriter {
+	return &lineWriter{emit: func(line []byte) error {
+		text := StripANSI(string(line))
+		if i := strings.LastIndexByte(text, '\r'); i >= 0 {
+			text = text[i+1:]
+		}
+		_, err := io.WriteString(out, text+"\n")
+		return err
+	}}
+}
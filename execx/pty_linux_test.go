@@ -0,0 +1,37 @@
+package execx
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPTYSession(t *testing.T) {
+	master, slave, err := openPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	script := `[ -t 0 ] || exit 3; [ -t 1 ] && [ -t 2 ] || exit 4; printf '\033[31mout\033[0m\n'; echo err >&2`
+	cmd := exec.CommandContext(context.Background(), "sh", "-c", script)
+	p := &ptySession{master: master, slave: slave, done: make(chan struct{})}
+	ec := &ExecCmd{Cmd: cmd, kill: configurePTYSession(cmd, time.Second)}
+	p.connect(ec)
+	cmd.Stdin = slave
+
+	var out strings.Builder
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	p.start(plainTextWriter(&out))
+	err = ec.Wait()
+	p.close()
+	if err != nil {
+		t.Fatalf("command did not run on a terminal: %v", err)
+	}
+	// The terminal merges both streams and turns \n into \r\n, which the writer drops
+	if got := out.String(); got != "out\nerr\n" {
+		t.Errorf("terminal output = %q", got)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package execx
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// openPTY reports that PTY mode is not supported on this platform
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, errors.New("PTY mode is only supported on Linux")
+}
+
+// configurePTYSession is never called on this platform
+func configurePTYSession(cmd *exec.Cmd, grace time.Duration) *killTimer {
+	return nil
+}
+
+// watchWindowSize is never called on this platform
+func watchWindowSize(master *os.File) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,52 @@
+package execx
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"\x1b[1;32m✓\x1b[m done", "✓ done"},
+		{"\x1b[2K\x1b[1Gprogress", "progress"},
+		{"\x1b]0;title\x07text", "text"},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+	}
+	for _, tt := range tests {
+		if got := StripANSI(tt.in); got != tt.want {
+			t.Errorf("StripANSI(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlainTextWriter(t *testing.T) {
+	var out strings.Builder
+	w := plainTextWriter(&out)
+	_, _ = w.Write([]byte("\x1b[32mok\x1b[0m\r\n 10%\r 50%\r\x1b[1m100%\x1b[0m\n"))
+	_, _ = w.Write([]byte("tail"))
+	_ = w.Flush()
+	if want := "ok\n100%\ntail\n"; out.String() != want {
+		t.Errorf("plain text = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPTYFallsBackToPipes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	// Captured output, like any output of a test binary, is not shown on a terminal
+	ctx := WithOptions(context.Background(), PTY())
+	res, err := NewExec().Capture(ctx, "sh", false, "-c", `if [ -t 1 ]; then echo tty; else echo pipe; fi`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout); got != "pipe\n" {
+		t.Errorf("command saw %q, want a pipe", got)
+	}
+}
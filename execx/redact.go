@@ -0,0 +1,171 @@
+package execx
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedMask replaces every secret in redacted text
+const redactedMask = "***"
+
+// DefaultRedactionPatterns match credentials commonly passed on command lines:
+// password=, token= and similar assignments, helm --set values with secret-looking
+// keys, sensitive flags, bearer tokens and passwords embedded in URLs
+var DefaultRedactionPatterns = []string{
+	`(?i)[\w.\-\[\]]*(?:password|passwd|secret|token|api[_-]?key|credentials?)[\w.\-\[\]]*=(?P<secret>'[^']*'|[^\s,&'"]+)`,
+	`(?i)--[\w-]*(?:password|secret|token)[\w-]*\s+(?P<secret>'[^']*'|[^\s-]\S*)`,
+	`(?i)\bbearer\s+(?P<secret>[\w\-.~+/]+=*)`,
+	`://[^/\s:@]+:(?P<secret>[^/\s@]+)@`,
+}
+
+// Redactor masks registered secret values and pattern matches in text.
+// A nil Redactor leaves text unchanged.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a Redactor for the given secret values
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.AddSecret(secrets...)
+	return r
+}
+
+// NewDefaultRedactor creates a Redactor for the given secret values
+// that also masks DefaultRedactionPatterns
+func NewDefaultRedactor(secrets ...string) *Redactor {
+	r := NewRedactor(secrets...)
+	if err := r.AddPattern(DefaultRedactionPatterns...); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+var defaultRedactor = NewDefaultRedactor()
+
+// DefaultRedactor returns the process-wide Redactor that NewDefaultExecutor and
+// the runners mask commands with when none is given. Secrets registered on it
+// with AddSecret are masked everywhere.
+func DefaultRedactor() *Redactor {
+	return defaultRedactor
+}
+
+// AddSecret registers values that must never appear in output
+func (r *Redactor) AddSecret(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		if v != "" {
+			r.secrets = append(r.secrets, v)
+		}
+	}
+	// Longest first so a secret containing another is masked whole
+	sort.Slice(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+}
+
+// AddPattern registers regular expressions whose matches must never appear in output.
+// When a pattern has a group named "secret", only that group is masked,
+// e.g. `password=(?P<secret>\S+)` keeps "password=" readable.
+func (r *Redactor) AddPattern(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, compiled...)
+	return nil
+}
+
+// Redact returns s with every registered secret and pattern match masked
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedMask)
+	}
+	for _, re := range r.patterns {
+		s = maskMatches(re, s)
+	}
+	return s
+}
+
+// maskMatches masks every match of re in s, or only its "secret" group if it has one
+func maskMatches(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	group := max(re.SubexpIndex("secret"), 0)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 || start < last {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(redactedMask)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// RedactArgs returns a copy of args with every registered secret masked.
+// A value following a sensitive flag, as in "--password x", is masked whole.
+func (r *Redactor) RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = r.Redact(arg)
+		if i > 0 && r.Redact(args[i-1]+" "+arg) != r.Redact(args[i-1])+" "+redacted[i] {
+			redacted[i] = redactedMask
+		}
+	}
+	return redacted
+}
+
+// RedactError returns err with its message redacted.
+// The original error stays reachable through errors.Is and errors.As.
+func (r *Redactor) RedactError(err error) error {
+	if err == nil || r == nil {
+		return err
+	}
+	msg := r.Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// redactedError carries a redacted message for an underlying error
+type redactedError struct {
+	msg string
+	err error
+}
+
+// Error implements the error interface
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the underlying error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
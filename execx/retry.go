@@ -0,0 +1,280 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// RetryPolicy configures how failed commands are retried
+type RetryPolicy struct {
+	Attempts     int           // Total attempts including the first; 1 or less disables retries
+	InitialDelay time.Duration // Delay before the first retry
+	MaxDelay     time.Duration // Upper bound for the delay between attempts; zero means no bound
+	Multiplier   float64       // Delay growth factor between attempts; 2 when zero
+	Jitter       float64       // Fraction of each delay randomized, e.g. 0.2 for +/-20%
+	Retryable    Classifier    // Decides which failures are retried; DefaultClassifier when nil
+}
+
+// DefaultRetryPolicy retries transient failures three times with exponential backoff
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:     4,
+	InitialDelay: 2 * time.Second,
+	MaxDelay:     30 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// PolicyFunc selects the retry policy for a command.
+// A zero RetryPolicy disables retries for that command.
+type PolicyFunc func(command string, args []string) RetryPolicy
+
+// Failure describes a failed attempt passed to a Classifier
+type Failure struct {
+	Command  string
+	Args     []string
+	ExitCode int    // Exit code, or -1 if the command did not exit normally
+	Stderr   string // Last lines written to stderr
+	Err      error
+}
+
+// Classifier reports whether a failure is worth retrying
+type Classifier func(f Failure) bool
+
+// transientPatterns match stderr output of network and registry hiccups
+var transientPatterns = []string{
+	`(?i)connection reset by peer`,
+	`(?i)connection refused`,
+	`(?i)i/o timeout`,
+	`(?i)tls handshake timeout`,
+	`(?i)temporary failure in name resolution`,
+	`(?i)no such host`,
+	`(?i)unexpected eof`,
+	`(?i)too many requests`,
+	`(?i)\b(429|500|502|503|504)\b.*(status|error|response)`,
+	`(?i)(bad gateway|service unavailable|gateway time-?out)`,
+}
+
+// DefaultClassifier retries failures whose stderr looks like a transient network error
+var DefaultClassifier = RetryOnStderr(transientPatterns...)
+
+// RetryOnStderr returns a Classifier matching stderr against regular expressions
+func RetryOnStderr(patterns ...string) Classifier {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return func(f Failure) bool {
+		for _, re := range res {
+			if re.MatchString(f.Stderr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnExitCodes returns a Classifier matching the given exit codes
+func RetryOnExitCodes(codes ...int) Classifier {
+	return func(f Failure) bool {
+		return slices.Contains(codes, f.ExitCode)
+	}
+}
+
+// AnyOf returns a Classifier that retries when any of the given classifiers does
+func AnyOf(classifiers ...Classifier) Classifier {
+	return func(f Failure) bool {
+		for _, c := range classifiers {
+			if c(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Retry is an Executor decorator that retries failed commands
+type Retry struct {
+	chain *Chain
+}
+
+// NewRetry creates a Retry applying the same policy to every command
+func NewRetry(next Executor, policy RetryPolicy) *Retry {
+	return NewRetryWithPolicies(next, func(string, []string) RetryPolicy {
+		return policy
+	})
+}
+
+// NewRetryWithPolicies creates a Retry that selects a policy per command
+func NewRetryWithPolicies(next Executor, policyFor PolicyFunc) *Retry {
+	return &Retry{
+		chain: NewChain(next, RetryInterceptor(policyFor)),
+	}
+}
+
+// Run executes the command, retrying failures the policy classifies as retryable
+func (r *Retry) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	return r.chain.Run(ctx, command, streamToLog, args...)
+}
+
+// Capture executes the command, retrying failures the policy classifies as retryable.
+// The wrapped executor must implement Capturer.
+func (r *Retry) Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	return r.chain.Capture(ctx, command, tee, args...)
+}
+
+// IsDryRun reports whether the wrapped executor is a DryRun
+func (r *Retry) IsDryRun() bool {
+	return r.chain.IsDryRun()
+}
+
+// execute retries inv, keeping the full Result
+func (r *Retry) execute(ctx context.Context, inv *Invocation) (*Result, error) {
+	return r.chain.execute(ctx, inv)
+}
+
+// retry runs inv until it succeeds, the failure is not retryable or attempts run out.
+// A supplied Stdin is read again from where it started by every attempt; when
+// it cannot be rewound, e.g. a pipe, the command is only run once. Tee writers
+// only receive the output of the attempt whose result is returned, while the
+// output a failed attempt showed is followed by the retry log line.
+func retry(ctx context.Context, policy RetryPolicy, inv *Invocation, next Handler) (*Result, error) {
+	if policy.Attempts <= 1 {
+		return next(ctx, inv)
+	}
+	classify := policy.Retryable
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+	rewind, rewindable := stdinRewinder(inv.Options.Stdin)
+
+	for n := 1; ; n++ {
+		if err := rewind(); err != nil {
+			return nil, fmt.Errorf("failed to rewind stdin for %q: %w", inv.Command, err)
+		}
+
+		tail := newLineTail(50)
+		tees := &heldTees{}
+		attempt := *inv
+		attempt.Options = tees.hold(inv.Options).With(TeeStderr(tail))
+		if inv.Options.PTY {
+			// A pseudo-terminal merges stderr into stdout
+			attempt.Options = attempt.Options.With(TeeStdout(tail))
+		}
+		res, err := next(ctx, &attempt)
+		if err == nil || ctx.Err() != nil {
+			tees.release(inv.Options)
+			return res, err
+		}
+
+		failure := Failure{
+			Command:  inv.Command,
+			Args:     inv.Args,
+			ExitCode: exitCode(err),
+			Stderr:   tail.String(),
+			Err:      err,
+		}
+		switch {
+		case !classify(failure):
+			tees.release(inv.Options)
+			return res, err
+		case !rewindable:
+			tees.release(inv.Options)
+			logx.FromContext(ctx).WarnContext(ctx, "⚠️ Not retrying command, its stdin cannot be read again",
+				"command", inv.Command,
+				"err", inv.Options.Redactor.Redact(err.Error()),
+			)
+			return res, err
+		case n >= policy.Attempts:
+			tees.release(inv.Options)
+			return res, fmt.Errorf("giving up after %d attempts: %w", n, err)
+		}
+
+		delay := policy.delay(n)
+		logx.FromContext(ctx).WarnContext(ctx, "🔁 Retrying command",
+			"command", inv.Command,
+			"attempt", n+1,
+			"of", policy.Attempts,
+			"delay", delay,
+			"err", inv.Options.Redactor.Redact(err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return res, fmt.Errorf("command %q canceled while waiting to retry: %w", inv.Command, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// stdinRewinder returns a function moving stdin back to where it is now, and
+// whether that is possible. Without a supplied stdin there is nothing to rewind.
+func stdinRewinder(stdin iox.Reader) (rewind func() error, ok bool) {
+	if stdin == nil {
+		return func() error { return nil }, true
+	}
+	seeker, ok := stdin.(io.Seeker)
+	if !ok {
+		return func() error { return nil }, false
+	}
+	// Pipes and terminals are files too, but fail to seek
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return func() error { return nil }, false
+	}
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}, true
+}
+
+// heldTees buffers what one attempt writes to the Stdout and Stderr tees
+type heldTees struct {
+	stdout, stderr bytes.Buffer
+}
+
+// hold returns opts with its tees replaced by the buffers
+func (h *heldTees) hold(opts Options) Options {
+	if opts.Stdout != nil {
+		opts.Stdout = &h.stdout
+	}
+	if opts.Stderr != nil {
+		opts.Stderr = &h.stderr
+	}
+	return opts
+}
+
+// release writes the buffered output to the tees of opts
+func (h *heldTees) release(opts Options) {
+	if opts.Stdout != nil {
+		_, _ = opts.Stdout.Write(h.stdout.Bytes())
+	}
+	if opts.Stderr != nil {
+		_, _ = opts.Stderr.Write(h.stderr.Bytes())
+	}
+}
+
+// delay returns the backoff before retry number n, starting at 1
+func (p RetryPolicy) delay(n int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	d := float64(p.InitialDelay) * math.Pow(multiplier, float64(n-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,143 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flaky returns a Handler failing with stderr until the given attempt, writing out to stdout every time
+func flaky(succeedAt int, stderr, out string, attempts *int) Handler {
+	return func(ctx context.Context, inv *Invocation) (*Result, error) {
+		*attempts++
+		if inv.Options.Stdout != nil {
+			_, _ = inv.Options.Stdout.Write([]byte(out))
+		}
+		if *attempts < succeedAt {
+			if inv.Options.Stderr != nil {
+				_, _ = inv.Options.Stderr.Write([]byte(stderr))
+			}
+			return &Result{}, errors.New("exit status 1")
+		}
+		return &Result{}, nil
+	}
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	tests := []struct {
+		stderr string
+		retry  bool
+	}{
+		{"dial tcp 10.0.0.1:443: connect: connection refused", true},
+		{"read: connection reset by peer", true},
+		{"net/http: TLS handshake timeout", true},
+		{"Error: failed to fetch: 503 Service Unavailable", true},
+		{"received unexpected HTTP status: 429 Too Many Requests", true},
+		{"lookup registry.example.com: no such host", true},
+		{"Error: chart requires kubeVersion >= 1.25", false},
+		{"undefined: foo", false},
+	}
+	for _, tt := range tests {
+		if got := DefaultClassifier(Failure{Stderr: tt.stderr}); got != tt.retry {
+			t.Errorf("DefaultClassifier(%q) = %v, want %v", tt.stderr, got, tt.retry)
+		}
+	}
+}
+
+func TestClassifiers(t *testing.T) {
+	c := AnyOf(RetryOnExitCodes(75), RetryOnStderr(`locked`))
+	if !c(Failure{ExitCode: 75}) || !c(Failure{Stderr: "database is locked"}) {
+		t.Error("AnyOf() does not retry a failure one classifier accepts")
+	}
+	if c(Failure{ExitCode: 1, Stderr: "boom"}) {
+		t.Error("AnyOf() retries a failure no classifier accepts")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, InitialDelay: time.Millisecond}
+	tests := []struct {
+		name      string
+		succeedAt int
+		stderr    string
+		attempts  int
+		err       string
+	}{
+		{"transient then success", 2, "connection reset by peer\n", 2, ""},
+		{"permanent", 5, "invalid argument\n", 1, "exit status 1"},
+		{"gives up", 5, "i/o timeout\n", 3, "giving up after 3 attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			var stdout bytes.Buffer
+			inv := &Invocation{Command: "helm", Options: Options{Stdout: &stdout}}
+			_, err := retry(context.Background(), policy, inv, flaky(tt.succeedAt, tt.stderr, "out\n", &attempts))
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("retry() error = %v, want %q", err, tt.err)
+			}
+			if got := stdout.String(); got != "out\n" {
+				t.Errorf("tee received %q, want the output of one attempt", got)
+			}
+		})
+	}
+}
+
+func TestRetryClassifiesPTYOutput(t *testing.T) {
+	var attempts int
+	// A pseudo-terminal merges stderr into stdout, so nothing reaches the stderr tee
+	handler := func(ctx context.Context, inv *Invocation) (*Result, error) {
+		attempts++
+		if attempts == 1 {
+			_, _ = inv.Options.Stdout.Write([]byte("Error: connection refused\n"))
+			return nil, errors.New("exit status 1")
+		}
+		return &Result{}, nil
+	}
+	inv := &Invocation{Command: "ko", Options: Options{PTY: true}}
+	policy := RetryPolicy{Attempts: 2, InitialDelay: time.Millisecond}
+	if _, err := retry(context.Background(), policy, inv, handler); err != nil || attempts != 2 {
+		t.Errorf("retry() = %v after %d attempts, want success after 2", err, attempts)
+	}
+}
+
+func TestRetryStdin(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, InitialDelay: time.Millisecond}
+	read := func(got *[]string) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			data, _ := io.ReadAll(inv.Options.Stdin)
+			*got = append(*got, string(data))
+			return nil, errors.New("connection refused")
+		}
+	}
+	failing := func(h Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			_, err := h(ctx, inv)
+			_, _ = inv.Options.Stderr.Write([]byte("connection refused\n"))
+			return nil, err
+		}
+	}
+
+	var rewound []string
+	reader := strings.NewReader("skip:payload")
+	_, _ = reader.Seek(5, io.SeekStart)
+	inv := &Invocation{Command: "kubectl", Options: Options{Stdin: reader}}
+	_, _ = retry(context.Background(), policy, inv, failing(read(&rewound)))
+	if strings.Join(rewound, ",") != "payload,payload,payload" {
+		t.Errorf("seekable stdin read %q, want the payload on every attempt", rewound)
+	}
+
+	var once []string
+	inv = &Invocation{Command: "kubectl", Options: Options{Stdin: io.MultiReader(strings.NewReader("payload"))}}
+	_, err := retry(context.Background(), policy, inv, failing(read(&once)))
+	if len(once) != 1 || err == nil || strings.Contains(err.Error(), "giving up") {
+		t.Errorf("unrewindable stdin ran %d attempts, error = %v, want one attempt", len(once), err)
+	}
+}
@@ -0,0 +1,150 @@
+package execx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommandLine renders a command with its working directory and environment
+// as a single POSIX shell line that can be pasted into a terminal to run it
+// again, e.g. cd charts && HELM_DEBUG=1 helm upgrade app . --set 'a=b c'.
+// Variables from BaseEnv are rendered before those from Env, while the rest
+// of the inherited environment is left out.
+func CommandLine(opts Options, command string, args ...string) string {
+	var b strings.Builder
+	if opts.Dir != "" {
+		b.WriteString("cd " + ShellQuote(opts.Dir) + " && ")
+	}
+	for _, kv := range opts.environment() {
+		key, value, _ := strings.Cut(kv, "=")
+		b.WriteString(key + "=" + ShellQuote(value) + " ")
+	}
+	// A leading word with "=" would be read as another assignment
+	if strings.Contains(command, "=") {
+		b.WriteString(quoteWord(command))
+	} else {
+		b.WriteString(ShellQuote(command))
+	}
+	for _, arg := range args {
+		b.WriteString(" " + ShellQuote(arg))
+	}
+	return b.String()
+}
+
+// ShellJoin renders argv as a POSIX shell line
+func ShellJoin(argv []string) string {
+	if len(argv) == 0 {
+		return ""
+	}
+	return CommandLine(Options{}, argv[0], argv[1:]...)
+}
+
+// ShellQuote quotes s so a POSIX shell reads it back as a single word
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		if !isShellSafe(r) {
+			return quoteWord(s)
+		}
+	}
+	return s
+}
+
+// quoteWord wraps s in single quotes, which keep everything but a single quote literal
+func quoteWord(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isShellSafe reports whether r never needs quoting in a POSIX shell word
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("_-./:,+@%=", r)
+}
+
+// ShellSplit parses a POSIX shell quoted string into arguments, so extra
+// arguments for a mage target can be given as one string, e.g.
+// `--set 'image.tag=v1 rc' --set-json '{"a": 1}'`. Quotes and backslashes
+// work as in a shell, but nothing is expanded: parameter expansion, command
+// substitution and operators such as | or ; are rejected rather than passed
+// on literally.
+func ShellSplit(s string) ([]string, error) {
+	var (
+		args    []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(s)
+		invalid = func(i int, what string) error {
+			return fmt.Errorf("cannot split %q: %s at offset %d", s, what, i)
+		}
+	)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, invalid(i, "unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				switch c := runes[i]; c {
+				case '\\':
+					// Inside double quotes a backslash only escapes these
+					if i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+						i++
+						if runes[i] != '\n' {
+							word.WriteRune(runes[i])
+						}
+						continue
+					}
+					word.WriteRune(c)
+				case '$', '`':
+					return nil, invalid(i, fmt.Sprintf("unsupported expansion %q", c))
+				default:
+					word.WriteRune(c)
+				}
+			}
+			if i == len(runes) {
+				return nil, invalid(len(runes), "unterminated double quote")
+			}
+			inWord = true
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, invalid(i, "trailing backslash")
+			}
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case strings.ContainsRune("|&;<>()$`", r):
+			return nil, invalid(i, fmt.Sprintf("unsupported shell syntax %q", r))
+		case r == '#' && !inWord:
+			return nil, invalid(i, "unsupported comment")
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,108 @@
+package execx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"plain", "plain"},
+		{"a=b,c:d/e.f@g+h%i", "a=b,c:d/e.f@g+h%i"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{`{"a": 1}`, `'{"a": 1}'`},
+		{"a\nb", "'a\nb'"},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{in: "", want: nil},
+		{in: "  a\tb\nc  ", want: []string{"a", "b", "c"}},
+		{in: `--set 'image.tag=v1 rc'`, want: []string{"--set", "image.tag=v1 rc"}},
+		{in: `--set-json '{"a": 1}'`, want: []string{"--set-json", `{"a": 1}`}},
+		{in: `"a \"b\" \\ \$c"`, want: []string{`a "b" \ $c`}},
+		{in: `"a\nb"`, want: []string{`a\nb`}},
+		{in: `a\ b c\\d`, want: []string{"a b", `c\d`}},
+		{in: `'' ""`, want: []string{"", ""}},
+		{in: `pre'quoted'post`, want: []string{"prequotedpost"}},
+		{in: "a#b", want: []string{"a#b"}},
+		{in: "line\\\ncontinued", want: []string{"linecontinued"}},
+		{in: "'open", wantErr: "unterminated single quote"},
+		{in: `"open`, wantErr: "unterminated double quote"},
+		{in: `trailing\`, wantErr: "trailing backslash"},
+		{in: "a | b", wantErr: "unsupported shell syntax"},
+		{in: "a; b", wantErr: "unsupported shell syntax"},
+		{in: "$HOME", wantErr: "unsupported shell syntax"},
+		{in: `"$HOME"`, wantErr: "unsupported expansion"},
+		{in: "`id`", wantErr: "unsupported shell syntax"},
+		{in: "a # comment", wantErr: "unsupported comment"},
+	}
+	for _, tt := range tests {
+		got, err := ShellSplit(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ShellSplit(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ShellSplit(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ShellSplit(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellJoinRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"helm", "upgrade", "app", "."},
+		{"helm", "--set", "image.tag=v1 rc", "--set-json", `{"a": 1}`},
+		{"sh", "-c", "echo it's $HOME"},
+		{"a=b", "c"},
+		{"empty", ""},
+	}
+	for _, argv := range tests {
+		line := ShellJoin(argv)
+		got, err := ShellSplit(line)
+		if err != nil {
+			t.Errorf("ShellSplit(ShellJoin(%q)) error = %v", argv, err)
+			continue
+		}
+		if !slices.Equal(got, argv) {
+			t.Errorf("ShellSplit(%q) = %q, want %q", line, got, argv)
+		}
+	}
+	if got := ShellJoin(nil); got != "" {
+		t.Errorf("ShellJoin(nil) = %q, want empty", got)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	opts := Options{
+		Dir:     "my charts",
+		Env:     []string{"HELM_DEBUG=1", "EMPTY="},
+		BaseEnv: []string{"PROFILE=dev", "HELM_DEBUG=0"},
+	}
+	want := `cd 'my charts' && PROFILE=dev HELM_DEBUG=1 EMPTY='' helm upgrade app . --set 'a=b c'`
+	if got := CommandLine(opts, "helm", "upgrade", "app", ".", "--set", "a=b c"); got != want {
+		t.Errorf("CommandLine() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package execx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	backgroundOnce sync.Once
+	backgroundCtx  context.Context
+)
+
+// Background returns a process-wide context that is canceled when the process
+// receives SIGINT or SIGTERM. Runners use it when no context is supplied, so
+// interrupting mage terminates running commands gracefully. After the first
+// signal the default handling is restored, so a second one ends the process.
+func Background() context.Context {
+	backgroundOnce.Do(func() {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			signal.Stop(signals)
+			cancel(fmt.Errorf("received signal %s", sig))
+		}()
+		backgroundCtx = ctx
+	})
+	return backgroundCtx
+}
@@ -0,0 +1,137 @@
+package execx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+const (
+	CIEnv    = "CI"          // Set to true by CI systems such as GitHub Actions and GitLab CI
+	StdinEnv = "EXECX_STDIN" // Default stdin policy: inherit or null, e.g. EXECX_STDIN=null
+)
+
+// ErrTerminalInput is wrapped by the error of a command that was stopped
+// because one of its processes tried to read from the terminal
+var ErrTerminalInput = errors.New("stopped waiting for terminal input")
+
+type stdinKind int
+
+const (
+	stdinDefault stdinKind = iota
+	stdinInherit
+	stdinNull
+	stdinFixed
+)
+
+// StdinPolicy decides what a command reads when no Stdin option is given.
+// The zero value defers to the executor, and then to DefaultStdinPolicy.
+type StdinPolicy struct {
+	kind    stdinKind
+	content []byte
+}
+
+// InheritStdin lets commands read our own stdin, so tools may prompt the user
+func InheritStdin() StdinPolicy {
+	return StdinPolicy{kind: stdinInherit}
+}
+
+// NullStdin connects commands to the null device, so reads see end of file
+// and a prompting tool fails instead of waiting
+func NullStdin() StdinPolicy {
+	return StdinPolicy{kind: stdinNull}
+}
+
+// FixedStdin feeds every command the same content, e.g. "y\n"
+func FixedStdin(content []byte) StdinPolicy {
+	return StdinPolicy{kind: stdinFixed, content: content}
+}
+
+// ParseStdinPolicy parses a policy name: inherit or null
+func ParseStdinPolicy(s string) (StdinPolicy, error) {
+	switch strings.ToLower(s) {
+	case "inherit":
+		return InheritStdin(), nil
+	case "null":
+		return NullStdin(), nil
+	}
+	return StdinPolicy{}, fmt.Errorf("unknown stdin policy %q, want inherit or null", s)
+}
+
+// DefaultStdinPolicy returns the policy named by StdinEnv. Otherwise commands
+// get NullStdin when CIEnv is true, as nobody can answer a prompt there,
+// and InheritStdin when it is not.
+func DefaultStdinPolicy() StdinPolicy {
+	if name := os.Getenv(StdinEnv); name != "" {
+		if p, err := ParseStdinPolicy(name); err == nil {
+			return p
+		}
+	}
+	if ci, _ := strconv.ParseBool(os.Getenv(CIEnv)); ci {
+		return NullStdin()
+	}
+	return InheritStdin()
+}
+
+// String returns the name of the policy
+func (p StdinPolicy) String() string {
+	switch p.kind {
+	case stdinInherit:
+		return "inherit"
+	case stdinNull:
+		return "null"
+	case stdinFixed:
+		return fmt.Sprintf("fixed(%d bytes)", len(p.content))
+	}
+	return "default"
+}
+
+// IsZero reports whether p defers to the executor's policy
+func (p StdinPolicy) IsZero() bool {
+	return p.kind == stdinDefault
+}
+
+// or returns p, or fallback when p is the zero value
+func (p StdinPolicy) or(fallback StdinPolicy) StdinPolicy {
+	if p.IsZero() {
+		return fallback
+	}
+	return p
+}
+
+// reader returns the stdin of a command; nil means the null device
+func (p StdinPolicy) reader() iox.Reader {
+	switch p.or(DefaultStdinPolicy()).kind {
+	case stdinNull:
+		return nil
+	case stdinFixed:
+		return bytes.NewReader(p.content)
+	}
+	return os.Stdin
+}
+
+// inherits reports whether commands read our own stdin under p
+func (p StdinPolicy) inherits() bool {
+	return p.or(DefaultStdinPolicy()).kind == stdinInherit
+}
+
+// stdin returns the standard input for a command run with o
+func (o Options) stdin() iox.Reader {
+	switch {
+	case o.Stdin != nil:
+		return o.Stdin
+	case o.StdinData != nil:
+		return bytes.NewReader(o.StdinData)
+	}
+	return o.StdinPolicy.reader()
+}
+
+// suppliesStdin reports whether o gives the command its input, rather than a StdinPolicy
+func (o Options) suppliesStdin() bool {
+	return o.Stdin != nil || o.StdinData != nil
+}
@@ -0,0 +1,107 @@
+package execx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// terminalPollInterval is how often the processes of a command are checked
+// for having been stopped by a terminal read
+const terminalPollInterval = 250 * time.Millisecond
+
+// watchTerminalInput stops cmd with an ErrTerminalInput cause as soon as one of
+// its processes is stopped by job control. That is what happens to a process
+// outside the foreground process group reading from the terminal, e.g. a helm
+// plugin opening /dev/tty to prompt for a password. It returns a function
+// ending the watch.
+func watchTerminalInput(cmd Commander, stop context.CancelCauseFunc) (done func()) {
+	ec, ok := cmd.(*ExecCmd)
+	// Only a command in its own process group is stopped instead of reading
+	if !ok || ec.Process == nil || ec.SysProcAttr == nil || !ec.SysProcAttr.Setpgid {
+		return func() {}
+	}
+	root := ec.Process.Pid
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(terminalPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+			pids, names := stoppedProcesses(root)
+			if len(pids) == 0 {
+				continue
+			}
+			stop(fmt.Errorf("%s (pid %d) %w; give it input with the Stdin or StdinFrom option, or run it interactively",
+				names[0], pids[0], ErrTerminalInput))
+			// A stopped process would only act on SIGTERM once continued
+			for _, pid := range pids {
+				_ = syscall.Kill(pid, syscall.SIGKILL)
+			}
+			return
+		}
+	}()
+	return func() { close(quit) }
+}
+
+// stoppedProcesses returns the stopped processes among root and its
+// descendants, with their names
+func stoppedProcesses(root int) (pids []int, names []string) {
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if name, state, ok := processState(pid); ok && state == "T" {
+			pids = append(pids, pid)
+			names = append(names, name)
+		}
+		queue = append(queue, childProcesses(pid)...)
+	}
+	return pids, names
+}
+
+// processState reads the name and state letter of a process from /proc
+func processState(pid int) (name, state string, ok bool) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", "", false
+	}
+	// The name is in parentheses and may itself contain spaces or parentheses
+	s := string(stat)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return "", "", false
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	return s[open+1 : end], fields[0], true
+}
+
+// childProcesses returns the children of every thread of a process
+func childProcesses(pid int) []int {
+	files, _ := filepath.Glob(fmt.Sprintf("/proc/%d/task/*/children", pid))
+	var children []int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(data)) {
+			if child, err := strconv.Atoi(field); err == nil {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
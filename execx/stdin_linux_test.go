@@ -0,0 +1,34 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessState(t *testing.T) {
+	name, state, ok := processState(os.Getpid())
+	if !ok || name == "" || state == "T" {
+		t.Errorf("processState(self) = %q, %q, %v, want a running process", name, state, ok)
+	}
+	if _, _, ok := processState(-1); ok {
+		t.Error("processState(-1) found a process")
+	}
+}
+
+func TestStoppedCommandFails(t *testing.T) {
+	// The shell stops itself the way job control stops a background terminal read
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = WithOptions(ctx, StdinFrom(NullStdin()))
+	start := time.Now()
+	err := NewExec().Run(ctx, "sh", false, "-c", `kill -STOP $$; echo continued`)
+	if !errors.Is(err, ErrTerminalInput) {
+		t.Fatalf("Run() error = %v, want ErrTerminalInput", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stopped command was detected after %s", elapsed)
+	}
+}
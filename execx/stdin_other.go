@@ -0,0 +1,11 @@
+//go:build !linux
+
+package execx
+
+import "context"
+
+// watchTerminalInput does nothing: processes stopped by a terminal read are
+// only detected on Linux
+func watchTerminalInput(cmd Commander, stop context.CancelCauseFunc) (done func()) {
+	return func() {}
+}
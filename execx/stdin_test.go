@@ -0,0 +1,64 @@
+package execx
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDefaultStdinPolicy(t *testing.T) {
+	tests := []struct {
+		stdin, ci string
+		want      string
+	}{
+		{"", "", "inherit"},
+		{"", "true", "null"},
+		{"", "false", "inherit"},
+		{"inherit", "true", "inherit"},
+		{"NULL", "", "null"},
+		{"bogus", "1", "null"},
+	}
+	for _, tt := range tests {
+		t.Setenv(StdinEnv, tt.stdin)
+		t.Setenv(CIEnv, tt.ci)
+		if got := DefaultStdinPolicy().String(); got != tt.want {
+			t.Errorf("%s=%q %s=%q: DefaultStdinPolicy() = %s, want %s", StdinEnv, tt.stdin, CIEnv, tt.ci, got, tt.want)
+		}
+	}
+}
+
+func TestParseStdinPolicy(t *testing.T) {
+	if p, err := ParseStdinPolicy("Inherit"); err != nil || p.String() != "inherit" {
+		t.Errorf("ParseStdinPolicy(Inherit) = %s, %v", p, err)
+	}
+	if _, err := ParseStdinPolicy("fixed"); err == nil {
+		t.Error("ParseStdinPolicy(fixed) succeeded")
+	}
+}
+
+func TestStdinPrecedence(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs cat")
+	}
+	executor := NewExec().WithStdinPolicy(FixedStdin([]byte("y\n")))
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"executor policy", nil, "y\n"},
+		{"option policy", []Option{StdinFrom(NullStdin())}, ""},
+		{"stdin option", []Option{StdinFrom(NullStdin()), StdinBytes([]byte("data"))}, "data"},
+		{"reader", []Option{Stdin(strings.NewReader("stream"))}, "stream"},
+	}
+	for _, tt := range tests {
+		res, err := executor.Capture(WithOptions(context.Background(), tt.opts...), "cat", false)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := string(res.Stdout); got != tt.want {
+			t.Errorf("%s: cat read %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package execx
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// lineTail is a writer that keeps only the last lines written to it
+type lineTail struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial bytes.Buffer
+}
+
+// newLineTail creates a lineTail holding at most max lines
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+// Write implements io.Writer
+func (t *lineTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial.Write(p)
+	for {
+		line, err := t.partial.ReadString('\n')
+		if err != nil {
+			// Keep the unterminated remainder for the next write,
+			// splitting it like lineWriter once it grows too long
+			for len(line) >= maxLineLength {
+				t.push(line[:maxLineLength])
+				line = line[maxLineLength:]
+			}
+			t.partial.Reset()
+			t.partial.WriteString(line)
+			break
+		}
+		t.push(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// push appends a line, dropping the oldest one when full
+func (t *lineTail) push(line string) {
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// Lines returns the retained lines, including an unterminated last line
+func (t *lineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if t.partial.Len() > 0 {
+		lines = append(lines, t.partial.String())
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// String returns the retained lines joined by newlines
+func (t *lineTail) String() string {
+	return strings.Join(t.Lines(), "\n")
+}
+
+// tailLines returns the last max lines of s
+func tailLines(s string, max int) []string {
+	t := newLineTail(max)
+	_, _ = t.Write([]byte(s))
+	return t.Lines()
+}
@@ -0,0 +1,58 @@
+package execx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLineTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		writes []string
+		want   []string
+	}{
+		{"empty", 3, nil, nil},
+		{"fewer than max", 3, []string{"a\nb\n"}, []string{"a", "b"}},
+		{"keeps the last", 2, []string{"a\nb\nc\nd\n"}, []string{"c", "d"}},
+		{"split across writes", 3, []string{"he", "llo\nwor", "ld\n"}, []string{"hello", "world"}},
+		{"unterminated last line", 2, []string{"a\nb\nc"}, []string{"b", "c"}},
+		{"crlf", 3, []string{"a\r\nb\r\n"}, []string{"a", "b"}},
+		{"blank lines", 3, []string{"a\n\nb\n"}, []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := newLineTail(tt.max)
+			for _, w := range tt.writes {
+				if n, err := tail.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if got := tail.Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	if got, want := tailLines("1\n2\n3\n4", 2), []string{"3", "4"}; !slices.Equal(got, want) {
+		t.Errorf("tailLines() = %q, want %q", got, want)
+	}
+}
+
+func TestLineTailSplitsLongLines(t *testing.T) {
+	tail := newLineTail(3)
+	chunk := strings.Repeat("x", maxLineLength/4)
+	for range 10 {
+		_, _ = tail.Write([]byte(chunk))
+	}
+	if n := tail.partial.Len(); n >= maxLineLength {
+		t.Errorf("partial line holds %d bytes, want less than %d", n, maxLineLength)
+	}
+	lines := tail.Lines()
+	if len(lines) != 3 || len(lines[0]) != maxLineLength || len(lines[2]) != 2*len(chunk) {
+		t.Errorf("Lines() lengths = %d, want two full lines and the remainder", len(lines))
+	}
+}
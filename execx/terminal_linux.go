@@ -0,0 +1,5 @@
+package execx
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TCGETS
@@ -0,0 +1,285 @@
+package execx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// Environment variables configuring the default tracer
+const (
+	TraceIDEnv       = "EXECX_TRACE_ID"        // 32 hex digits shared by all spans, e.g. to stitch CI jobs together
+	TraceFileEnv     = "EXECX_TRACE_FILE"      // JSON-lines file spans are appended to
+	TraceOTLPFileEnv = "EXECX_TRACE_OTLP_FILE" // OTLP-JSON file spans are appended to
+)
+
+// traceIDPattern matches a valid non-zero trace ID
+var traceIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// SpanExporter receives every span when it ends
+type SpanExporter interface {
+	ExportSpan(s *Span) error
+}
+
+// Span is a timed operation within a trace, such as a runner method or a command.
+// A nil Span ignores every call, so callers need not check whether tracing is enabled.
+type Span struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	ParentID   string         `json:"parentSpanId,omitempty"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SetAttributes records key-value pairs on the span, given as alternating keys and values
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.Attributes[fmt.Sprint(attrs[i])] = attrs[i+1]
+	}
+}
+
+// Fail marks the span as failed with err and returns err
+func (s *Span) Fail(err error) error {
+	if s == nil || err == nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Error = err.Error()
+	return err
+}
+
+// Finish ends the span and hands it to the tracer's exporters.
+// Only the first call has an effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.export(s)
+}
+
+// Duration returns the time between start and end of a finished span
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Tracer creates spans sharing one trace ID and exports them when they end.
+// A nil Tracer creates no spans.
+type Tracer struct {
+	traceID   string
+	exporters []SpanExporter
+	mu        sync.Mutex
+}
+
+// NewTracer creates a Tracer exporting spans to exporters.
+// An empty traceID is replaced by a random one.
+func NewTracer(traceID string, exporters ...SpanExporter) (*Tracer, error) {
+	if traceID == "" {
+		traceID = randomID(16)
+	}
+	if !traceIDPattern.MatchString(traceID) || traceID == "00000000000000000000000000000000" {
+		return nil, fmt.Errorf("invalid trace ID %q: want 32 lowercase hex digits", traceID)
+	}
+	return &Tracer{traceID: traceID, exporters: exporters}, nil
+}
+
+// TraceID returns the ID shared by all spans of t
+func (t *Tracer) TraceID() string {
+	if t == nil {
+		return ""
+	}
+	return t.traceID
+}
+
+// Start begins a span named name. It becomes a child of the span in ctx,
+// and the returned context carries the new span for nested operations.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		TraceID:    t.traceID,
+		SpanID:     randomID(8),
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]any),
+		tracer:     t,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.ParentID = parent.SpanID
+	}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// export passes a finished span to every exporter, one span at a time
+func (t *Tracer) export(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.exporters {
+		if err := e.ExportSpan(s); err != nil {
+			logx.Default().Warn("⚠️ Failed to export trace span", "span", s.Name, "err", err)
+		}
+	}
+}
+
+// spanKey is the context key for the current span
+type spanKey struct{}
+
+// SpanFromContext returns the current span in ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+var (
+	tracerMu     sync.Mutex
+	tracerLoaded bool
+	tracer       *Tracer
+)
+
+// SetTracer replaces the process-wide tracer used by StartSpan and
+// TracingInterceptor. A nil tracer disables tracing.
+func SetTracer(t *Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	tracer, tracerLoaded = t, true
+}
+
+// DefaultTracer returns the process-wide tracer. Unless set with SetTracer it is
+// configured from TraceFileEnv, TraceOTLPFileEnv and TraceIDEnv, and is nil when
+// neither file is set.
+func DefaultTracer() *Tracer {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	if !tracerLoaded {
+		tracerLoaded = true
+		t, err := tracerFromEnv()
+		if err != nil {
+			logx.Default().Warn("⚠️ Tracing disabled", "err", err)
+		}
+		tracer = t
+	}
+	return tracer
+}
+
+// tracerFromEnv builds a tracer writing to the files named in the environment
+func tracerFromEnv() (*Tracer, error) {
+	var exporters []SpanExporter
+	if path := os.Getenv(TraceFileEnv); path != "" {
+		f, err := openTraceFile(path)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, NewJSONExporter(f))
+	}
+	if path := os.Getenv(TraceOTLPFileEnv); path != "" {
+		f, err := openTraceFile(path)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, NewOTLPExporter(f))
+	}
+	if len(exporters) == 0 {
+		return nil, nil
+	}
+	return NewTracer(os.Getenv(TraceIDEnv), exporters...)
+}
+
+// openTraceFile opens path for appending, so several runs can share one file
+func openTraceFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return f, nil
+}
+
+// StartSpan begins a span with the default tracer, see Tracer.Start.
+// Runner methods use it so the commands they execute are nested under them.
+func StartSpan(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	return DefaultTracer().Start(ctx, name, attrs...)
+}
+
+// StartTarget begins the span of a mage target, e.g. "helm:upgrade". It is
+// the root of the trace of a mage run: the spans of runner methods and the
+// commands called with the returned context are nested under it.
+func StartTarget(ctx context.Context, name string) (context.Context, *Span) {
+	return StartSpan(ctx, "mage "+name, "mage.target", name)
+}
+
+// TracingInterceptor records every command as a span of t, nested under the
+// span in the context. A nil t uses DefaultTracer at the time of each command.
+func TracingInterceptor(t *Tracer) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inv *Invocation) (*Result, error) {
+			tr := t
+			if tr == nil {
+				tr = DefaultTracer()
+			}
+			if tr == nil {
+				return next(ctx, inv)
+			}
+
+			cwd := inv.Options.Dir
+			if cwd == "" {
+				cwd, _ = os.Getwd()
+			}
+			ctx, span := tr.Start(ctx, metricsKey(inv),
+				"command", inv.Command,
+				"args", inv.Options.Redactor.RedactArgs(inv.Args),
+				"cwd", cwd,
+			)
+			res, err := next(ctx, inv)
+			span.SetAttributes("exit_code", exitCode(err))
+			if res != nil && res.Cached {
+				span.SetAttributes("cached", true)
+			}
+			if res != nil && res.Usage != nil {
+				span.SetAttributes(
+					"cpu_user_ms", res.Usage.UserTime.Milliseconds(),
+					"cpu_system_ms", res.Usage.SystemTime.Milliseconds(),
+					"max_rss_bytes", res.Usage.MaxRSS,
+				)
+			}
+			_ = span.Fail(inv.Options.Redactor.RedactError(err))
+			span.Finish()
+			return res, err
+		}
+	}
+}
+
+// randomID returns n random bytes as lowercase hex
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package execx
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/iox"
+)
+
+// JSONExporter writes every span as one JSON object per line
+type JSONExporter struct {
+	mu  sync.Mutex
+	out iox.Writer
+}
+
+// NewJSONExporter creates a JSONExporter writing to out
+func NewJSONExporter(out iox.Writer) *JSONExporter {
+	return &JSONExporter{out: out}
+}
+
+// ExportSpan implements SpanExporter
+func (e *JSONExporter) ExportSpan(s *Span) error {
+	line, err := json.Marshal(struct {
+		*Span
+		DurationMS float64 `json:"durationMs"`
+	}{s, float64(s.Duration().Microseconds()) / 1000})
+	if err != nil {
+		return fmt.Errorf("failed to encode span: %w", err)
+	}
+	return e.write(line)
+}
+
+// write writes line followed by a newline in a single call
+func (e *JSONExporter) write(line []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.out.Write(append(line, '\n'))
+	return err
+}
+
+// OTLPExporter writes every span as an OTLP-JSON ExportTraceServiceRequest per line,
+// the format read by the OpenTelemetry Collector's otlpjsonfile receiver
+type OTLPExporter struct {
+	JSONExporter
+}
+
+// NewOTLPExporter creates an OTLPExporter writing to out
+func NewOTLPExporter(out iox.Writer) *OTLPExporter {
+	return &OTLPExporter{JSONExporter{out: out}}
+}
+
+// OTLP status codes and span kinds
+const (
+	otlpStatusOK       = 1
+	otlpStatusError    = 2
+	otlpKindInternal   = 1
+	otlpScopeName      = "github.com/vinaycharlie01/go-mage-shared/execx"
+	otlpServiceName    = "mage"
+	otlpServiceNameKey = "service.name"
+)
+
+// ExportSpan implements SpanExporter
+func (e *OTLPExporter) ExportSpan(s *Span) error {
+	status := map[string]any{"code": otlpStatusOK}
+	if s.Error != "" {
+		status = map[string]any{"code": otlpStatusError, "message": s.Error}
+	}
+	span := map[string]any{
+		"traceId":           s.TraceID,
+		"spanId":            s.SpanID,
+		"name":              s.Name,
+		"kind":              otlpKindInternal,
+		"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.Attributes),
+		"status":            status,
+	}
+	if s.ParentID != "" {
+		span["parentSpanId"] = s.ParentID
+	}
+
+	line, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{otlpServiceNameKey: otlpServiceName}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": otlpScopeName},
+				"spans": []any{span},
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode span: %w", err)
+	}
+	return e.write(line)
+}
+
+// otlpAttributes converts attributes to OTLP key-value pairs
+func otlpAttributes(attrs map[string]any) []any {
+	kvs := make([]any, 0, len(attrs))
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		kvs = append(kvs, map[string]any{"key": k, "value": otlpValue(attrs[k])})
+	}
+	return kvs
+}
+
+// otlpValue converts a Go value to an OTLP AnyValue
+func otlpValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = otlpValue(s)
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
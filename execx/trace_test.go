@@ -0,0 +1,181 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spanRecorder is a SpanExporter keeping every span it receives
+type spanRecorder struct {
+	spans []*Span
+}
+
+// ExportSpan implements SpanExporter
+func (r *spanRecorder) ExportSpan(s *Span) error {
+	r.spans = append(r.spans, s)
+	return nil
+}
+
+func TestNewTracer(t *testing.T) {
+	tests := []struct {
+		traceID string
+		valid   bool
+	}{
+		{"", true},
+		{"4bf92f3577b34da6a3ce929d0e0e4736", true},
+		{"4BF92F3577B34DA6A3CE929D0E0E4736", false},
+		{"4bf92f3577b34da6", false},
+		{"00000000000000000000000000000000", false},
+	}
+	for _, tt := range tests {
+		tr, err := NewTracer(tt.traceID)
+		if (err == nil) != tt.valid {
+			t.Errorf("NewTracer(%q) error = %v, want valid %v", tt.traceID, err, tt.valid)
+		}
+		if err == nil && len(tr.TraceID()) != 32 {
+			t.Errorf("NewTracer(%q).TraceID() = %q", tt.traceID, tr.TraceID())
+		}
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tr *Tracer
+	ctx, span := tr.Start(context.Background(), "noop")
+	span.SetAttributes("key", "value")
+	if err := span.Fail(errors.New("boom")); err == nil {
+		t.Error("Fail() on a nil span dropped the error")
+	}
+	span.Finish()
+	if SpanFromContext(ctx) != nil || tr.TraceID() != "" {
+		t.Error("nil Tracer created a span")
+	}
+}
+
+func TestTracingInterceptor(t *testing.T) {
+	rec := &spanRecorder{}
+	tr, err := NewTracer("", rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dry := NewDryRunWithWriter(&bytes.Buffer{}).Respond(&Result{Command: "bin/helm", Args: []string{"upgrade"}, ExitCode: 2})
+	chain := NewChain(dry, TracingInterceptor(tr))
+
+	ctx, target := tr.Start(context.Background(), "mage helm:upgrade")
+	ctx = WithOptions(ctx, Dir("charts"), DefaultRedaction())
+	_ = chain.Run(ctx, "bin/helm", false, "upgrade", "app", "--set", "db.password=hunter2")
+	target.Finish()
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("exported %d spans, want the command and its target", len(rec.spans))
+	}
+	cmd := rec.spans[0]
+	if cmd.Name != "helm upgrade" || cmd.ParentID != target.SpanID || cmd.TraceID != tr.TraceID() {
+		t.Errorf("command span = %+v, want helm upgrade nested under %s", cmd, target.SpanID)
+	}
+	if cmd.Attributes["exit_code"] != 2 || cmd.Attributes["cwd"] != "charts" || cmd.Error == "" {
+		t.Errorf("command span attributes = %v, error = %q", cmd.Attributes, cmd.Error)
+	}
+	if args := cmd.Attributes["args"].([]string); !slices.Contains(args, "db.password=***") {
+		t.Errorf("args = %q, want the secret masked", args)
+	}
+	if cmd.End.Before(cmd.Start) || target.End.Before(cmd.End) {
+		t.Errorf("command span %s - %s does not end within its target", cmd.Start, cmd.End)
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	span := &Span{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		Name:       "helm upgrade",
+		Start:      start,
+		End:        start.Add(1500 * time.Microsecond),
+		Attributes: map[string]any{"exit_code": 0},
+	}
+	var out bytes.Buffer
+	if err := NewJSONExporter(&out).ExportSpan(span); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("exported %q: %v", out.String(), err)
+	}
+	if got["name"] != "helm upgrade" || got["durationMs"] != 1.5 || got["traceId"] != span.TraceID {
+		t.Errorf("exported %v", got)
+	}
+	if _, ok := got["parentSpanId"]; ok {
+		t.Errorf("exported a parent of a root span: %v", got)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	span := &Span{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		ParentID:   "a3ce929d0e0e4736",
+		Name:       "ko build",
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: map[string]any{"args": []string{"build", "./cmd"}, "exit_code": 1, "cached": true},
+		Error:      "exit status 1",
+	}
+	var out bytes.Buffer
+	if err := NewOTLPExporter(&out).ExportSpan(span); err != nil {
+		t.Fatal(err)
+	}
+	line := out.String()
+	for _, want := range []string{
+		`"parentSpanId":"a3ce929d0e0e4736"`,
+		`"startTimeUnixNano":"1700000000000000000"`,
+		`"endTimeUnixNano":"1700000001000000000"`,
+		`"status":{"code":2,"message":"exit status 1"}`,
+		`{"key":"args","value":{"arrayValue":{"values":[{"stringValue":"build"},{"stringValue":"./cmd"}]}}}`,
+		`{"key":"cached","value":{"boolValue":true}}`,
+		`{"key":"exit_code","value":{"intValue":"1"}}`,
+		`{"key":"service.name","value":{"stringValue":"mage"}}`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("exported %s\nwant it to contain %s", line, want)
+		}
+	}
+	if !strings.HasSuffix(line, "}\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("exported %q, want a single line", line)
+	}
+}
+
+func TestTracerFromEnv(t *testing.T) {
+	t.Setenv(TraceFileEnv, "")
+	t.Setenv(TraceOTLPFileEnv, "")
+	if tr, err := tracerFromEnv(); tr != nil || err != nil {
+		t.Errorf("tracerFromEnv() without files = %v, %v, want tracing disabled", tr, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	t.Setenv(TraceFileEnv, path)
+	t.Setenv(TraceIDEnv, "4bf92f3577b34da6a3ce929d0e0e4736")
+	for range 2 {
+		tr, err := tracerFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, span := tr.Start(context.Background(), "mage tools")
+		span.Finish()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`); n != 2 {
+		t.Errorf("trace file has %d spans of the trace, want both runs appended:\n%s", n, data)
+	}
+}
@@ -0,0 +1,46 @@
+package execx
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Usage holds the resources consumed by a finished command.
+// Fields the platform does not report are zero.
+type Usage struct {
+	UserTime   time.Duration // CPU time spent in user mode
+	SystemTime time.Duration // CPU time spent in the kernel
+	MaxRSS     int64         // Peak resident set size in bytes
+	InBlocks   int64         // Block input operations
+	OutBlocks  int64         // Block output operations
+}
+
+// CPUTime returns the total CPU time
+func (u *Usage) CPUTime() time.Duration {
+	return u.UserTime + u.SystemTime
+}
+
+// LogValue implements slog.LogValuer
+func (u *Usage) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Duration("userTime", u.UserTime),
+		slog.Duration("systemTime", u.SystemTime),
+		slog.Int64("maxRSS", u.MaxRSS),
+		slog.Int64("inBlocks", u.InBlocks),
+		slog.Int64("outBlocks", u.OutBlocks),
+	)
+}
+
+// usageReporter is implemented by Commanders that can report resource usage after Wait
+type usageReporter interface {
+	Usage() *Usage
+}
+
+// Usage returns the resources consumed by the command after Wait, or nil
+// when it has not finished
+func (e *ExecCmd) Usage() *Usage {
+	if e.Cmd.ProcessState == nil {
+		return nil
+	}
+	return processUsage(e.Cmd.ProcessState)
+}
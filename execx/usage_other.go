@@ -0,0 +1,14 @@
+//go:build !unix
+
+package execx
+
+import "os"
+
+// processUsage reports the CPU times of a finished process.
+// Memory and I/O counters are not available on this platform.
+func processUsage(state *os.ProcessState) *Usage {
+	return &Usage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+}
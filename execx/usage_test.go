@@ -0,0 +1,94 @@
+package execx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+func TestExecReportsUsage(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("resource usage is not reported on " + runtime.GOOS)
+	}
+	res, err := NewExec().Capture(context.Background(), "sh", false, "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Usage == nil {
+		t.Fatal("Capture() reported no usage")
+	}
+	// A shell needs more than 100KiB and far less than 1GiB; the bounds catch
+	// a peak RSS left in kilobytes or scaled twice
+	if res.Usage.MaxRSS < 100<<10 || res.Usage.MaxRSS > 1<<30 {
+		t.Errorf("MaxRSS = %d bytes", res.Usage.MaxRSS)
+	}
+	if res.Usage.CPUTime() <= 0 {
+		t.Errorf("CPUTime() = %s, want the time the loop took", res.Usage.CPUTime())
+	}
+}
+
+// usageExecutor is a Capturer reporting fixed usage for every command
+type usageExecutor struct {
+	usage map[string]*Usage
+}
+
+// Run implements Executor
+func (e usageExecutor) Run(ctx context.Context, command string, streamToLog bool, args ...string) error {
+	_, err := e.Capture(ctx, command, false, args...)
+	return err
+}
+
+// Capture implements Capturer
+func (e usageExecutor) Capture(ctx context.Context, command string, tee bool, args ...string) (*Result, error) {
+	return &Result{Command: command, Args: args, Duration: time.Second, Usage: e.usage[command]}, nil
+}
+
+func TestUsageInterceptor(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := logx.NewContext(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+	base := usageExecutor{usage: map[string]*Usage{"ko": {UserTime: time.Second, MaxRSS: 2 << 20}}}
+	chain := NewChain(base, UsageInterceptor())
+
+	_, _ = chain.Capture(ctx, "helm", false, "list")
+	if logs.Len() != 0 {
+		t.Errorf("logged %q for a command without usage", logs.String())
+	}
+	_, _ = chain.Capture(ctx, "ko", false, "build", "./cmd")
+	for _, want := range []string{`command="ko build"`, "duration=1s", "usage.userTime=1s", "usage.maxRSS=2097152"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logged %q, want %s", logs.String(), want)
+		}
+	}
+}
+
+func TestMetricsHeaviest(t *testing.T) {
+	m := NewMetrics()
+	base := usageExecutor{usage: map[string]*Usage{
+		"go":   {UserTime: time.Second, MaxRSS: 500 << 20},
+		"helm": {MaxRSS: 50 << 20},
+		"ko":   {SystemTime: time.Second, MaxRSS: 800 << 20},
+		"git":  {MaxRSS: 50 << 20},
+	}}
+	chain := NewChain(base, MetricsInterceptor(m))
+	ctx := context.Background()
+	for _, argv := range [][]string{{"go", "build"}, {"go", "build"}, {"helm", "lint"}, {"ko", "build"}, {"git", "status"}} {
+		_, _ = chain.Capture(ctx, argv[0], false, argv[1:]...)
+	}
+
+	if got, want := m.Heaviest(3), []string{"ko build", "go build", "git status"}; !slices.Equal(got, want) {
+		t.Errorf("Heaviest(3) = %q, want %q", got, want)
+	}
+	if got := m.Heaviest(10); len(got) != 4 {
+		t.Errorf("Heaviest(10) = %q, want every command", got)
+	}
+	if s := m.Snapshot()["go build"]; s.Calls != 2 || s.CPU != 2*time.Second || s.MaxRSS != 500<<20 {
+		t.Errorf(`stats["go build"] = %+v`, s)
+	}
+}
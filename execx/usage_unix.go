@@ -0,0 +1,29 @@
+//go:build unix
+
+package execx
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// processUsage reads the rusage of a finished process
+func processUsage(state *os.ProcessState) *Usage {
+	u := &Usage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return u
+	}
+	// ru_maxrss is in bytes on Darwin and in kilobytes elsewhere
+	u.MaxRSS = int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		u.MaxRSS *= 1024
+	}
+	u.InBlocks = int64(rusage.Inblock)
+	u.OutBlocks = int64(rusage.Oublock)
+	return u
+}
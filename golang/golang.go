@@ -2,84 +2,283 @@ package golang
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
 
+	"github.com/vinaycharlie01/go-mage-shared/envx"
 	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/lockx"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
 )
 
 // GoRunner handles Go command execution with dependency injection
 type GoRunner struct {
 	executor execx.Executor
+	tools    *toolx.Set
+	logger   *slog.Logger
+	passEnv  []string
+	profile  *envx.Profile
+	locking  bool
+	lockOpts []lockx.Option
 }
 
-// NewGoRunner creates a new GoRunner with the default executor
-func NewGoRunner() *GoRunner {
-	return &GoRunner{
-		executor: execx.NewExec(),
+// Option configures a GoRunner
+type Option func(*GoRunner)
+
+// WithRetry retries subcommands that download modules through the proxy
+// with the policies from RetryPolicyFor
+func WithRetry() Option {
+	return func(g *GoRunner) {
+		g.executor = execx.NewRetryWithPolicies(g.executor, RetryPolicyFor)
+	}
+}
+
+// WithRedactor masks the secrets known to r in echoed commands,
+// streamed and captured output and errors of every command the runner executes,
+// instead of execx.DefaultRedactor.
+func WithRedactor(r *execx.Redactor) Option {
+	return func(g *GoRunner) {
+		g.executor = execx.NewChain(g.executor, execx.RedactionInterceptor(r))
+	}
+}
+
+// WithLocking serializes RunBuild calls writing to the same output directory,
+// and InstallTools calls installing into the same bin directory, across
+// processes on this machine
+func WithLocking(opts ...lockx.Option) Option {
+	return func(g *GoRunner) {
+		g.locking = true
+		g.lockOpts = opts
+	}
+}
+
+// Tools lists the binaries the runner executes and the versions it supports
+var Tools = []toolx.Requirement{
+	{Tool: "go", Constraint: ">=1.21"},
+	{Tool: "golangci-lint"},
+	{Tool: "gofmt"},
+	{Tool: "goimports"},
+}
+
+// PassEnv lists the variables the runner's tools legitimately read from our
+// environment: where modules and build results are cached and how private
+// modules are fetched. Hermetic commands inherit them, see execx.Hermetic.
+var PassEnv = []string{
+	"GOPATH", "GOCACHE", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GONOPROXY", "GONOSUMDB", "GOSUMDB", "GOINSECURE", "GOAUTH",
+	"GIT_ASKPASS", "GIT_SSH_COMMAND", "SSH_AUTH_SOCK", "NETRC",
+	"GOLANGCI_LINT_CACHE",
+}
+
+// WithPassEnv declares more variables the runner's commands inherit when they
+// run hermetic, e.g. "KUBECONFIG"
+func WithPassEnv(names ...string) Option {
+	return func(g *GoRunner) {
+		g.passEnv = append(g.passEnv, names...)
+	}
+}
+
+// WithProfile runs the runner's commands with the variables of p, e.g. GOPRIVATE
+func WithProfile(p *envx.Profile) Option {
+	return func(g *GoRunner) {
+		g.profile = p
+	}
+}
+
+// WithBinary runs tool from path instead of looking it up in PATH
+func WithBinary(tool, path string) Option {
+	return func(g *GoRunner) {
+		g.tools.UsePath(tool, path)
+	}
+}
+
+// WithToolCheck verifies that each tool is installed and satisfies Tools
+// before it is first used, with toolx.DefaultRegistry when registry is nil
+func WithToolCheck(registry *toolx.Registry) Option {
+	return func(g *GoRunner) {
+		if registry == nil {
+			registry = toolx.DefaultRegistry()
+		}
+		g.tools.VerifyWith(registry)
+	}
+}
+
+// WithoutToolCheck runs tools without verifying them first
+func WithoutToolCheck() Option {
+	return func(g *GoRunner) {
+		g.tools.VerifyWith(nil)
+	}
+}
+
+// WithLogger reports the runner's progress, and the output of commands run
+// with streamToLog, to l instead of logx.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(g *GoRunner) {
+		g.logger = l
+	}
+}
+
+// WithLogMode reports the runner's progress to stderr in mode, e.g. logx.Quiet
+func WithLogMode(mode logx.Mode) Option {
+	return WithLogger(logx.New(os.Stderr, mode))
+}
+
+// NewGoRunner creates a new GoRunner with the default executor.
+// Commands are only printed when execx.DryRunEnv is set; otherwise each tool
+// is verified with toolx.DefaultRegistry before it is first used.
+func NewGoRunner(opts ...Option) *GoRunner {
+	executor := execx.NewDefaultExecutor()
+	if !execx.IsDryRun(executor) {
+		opts = append([]Option{WithToolCheck(nil)}, opts...)
 	}
+	return NewGoRunnerWithExecutor(executor, opts...)
 }
 
 // NewGoRunnerWithExecutor creates a new GoRunner with a custom executor
-func NewGoRunnerWithExecutor(executor execx.Executor) *GoRunner {
-	return &GoRunner{
+func NewGoRunnerWithExecutor(executor execx.Executor, opts ...Option) *GoRunner {
+	g := &GoRunner{
 		executor: executor,
+		tools:    toolx.NewSet(Tools...),
+		passEnv:  slices.Clone(PassEnv),
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// start begins the span of a runner method, makes the runner's logger the
+// one its commands report to and gives them the variables they need.
+// Secrets are masked with execx.DefaultRedactor unless ctx sets a Redactor.
+func (g *GoRunner) start(ctx context.Context, name string) (context.Context, *execx.Span) {
+	if g.logger != nil {
+		ctx = logx.NewContext(ctx, g.logger)
+	}
+	ctx = execx.WithOptions(ctx, execx.PassEnv(g.passEnv...), execx.DefaultRedaction())
+	ctx = g.profile.Context(ctx)
+	return execx.StartSpan(ctx, name)
+}
+
+// RetryPolicyFor returns the default retry policy for a go subcommand.
+// Subcommands that download modules through the proxy are retried.
+func RetryPolicyFor(command string, args []string) execx.RetryPolicy {
+	// The command may be a path, e.g. the pinned bin/go or one set WithBinary
+	if strings.TrimSuffix(filepath.Base(command), ".exe") != "go" || len(args) == 0 {
+		return execx.RetryPolicy{}
+	}
+	switch args[0] {
+	case "install", "get":
+		return execx.DefaultRetryPolicy
+	case "mod":
+		if len(args) > 1 && (args[1] == "download" || args[1] == "tidy") {
+			return execx.DefaultRetryPolicy
+		}
+	}
+	return execx.RetryPolicy{}
 }
 
 // RunTests runs Go tests with given arguments
 func (g *GoRunner) RunTests(args ...string) error {
-	slog.Info("🧪 Running Go Tests...")
+	return g.RunTestsContext(execx.Background(), args...)
+}
+
+// RunTestsContext is like RunTests but stops its commands when ctx is done
+func (g *GoRunner) RunTestsContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunTests")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "test", "🧪 Running Go Tests...")
 	defaultArgs := []string{"test", "./..."}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "go", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Tests passed", "duration", time.Since(start))
+	step.Done("✅ Tests passed")
 	return nil
 }
 
 // RunLint runs golangci-lint with given arguments
 func (g *GoRunner) RunLint(args ...string) error {
-	slog.Info("🔍 Running Go Linter...")
+	return g.RunLintContext(execx.Background(), args...)
+}
+
+// RunLintContext is like RunLint but stops its commands when ctx is done
+func (g *GoRunner) RunLintContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunLint")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "golangci-lint")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "golangci-lint", "run", "🔍 Running Go Linter...")
 	defaultArgs := []string{"run", "--timeout=5m"}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "golangci-lint", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Lint passed", "duration", time.Since(start))
+	step.Done("✅ Lint passed")
 	return nil
 }
 
 // RunInstall installs Go packages
 func (g *GoRunner) RunInstall(pkgs []string, args ...string) error {
+	return g.RunInstallContext(execx.Background(), pkgs, args...)
+}
+
+// RunInstallContext is like RunInstall but stops its commands when ctx is done.
+// Per-invocation options in ctx apply, e.g. execx.Env("GOBIN=...").
+func (g *GoRunner) RunInstallContext(ctx context.Context, pkgs []string, args ...string) error {
 	if len(pkgs) == 0 {
 		return fmt.Errorf("no package specified for installation")
 	}
 
-	slog.Info("📦 Installing Go packages individually...", "packages", pkgs)
+	ctx, span := g.start(ctx, "GoRunner.RunInstall")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "install", "📦 Installing Go packages individually...", "packages", pkgs)
 
-	start := time.Now()
 	for _, pkg := range pkgs {
 		cmdArgs := append([]string{"install", pkg}, args...)
-		if err := g.executor.Run(context.Background(), "go", false, cmdArgs...); err != nil {
-			return fmt.Errorf("failed to install %s: %w", pkg, err)
+		if err := g.executor.Run(ctx, bin, false, cmdArgs...); err != nil {
+			return span.Fail(step.Fail(fmt.Errorf("failed to install %s: %w", pkg, err)))
 		}
 	}
 
-	slog.Info("✅ Installation complete", "duration", time.Since(start))
+	step.Done("✅ Installation complete")
 	return nil
 }
 
 // RunModTasks runs `go mod tidy` and `go mod verify` sequentially
 func (g *GoRunner) RunModTasks() error {
-	slog.Info("📦 Running Go module maintenance (tidy & verify)...")
+	return g.RunModTasksContext(execx.Background())
+}
 
-	start := time.Now()
+// RunModTasksContext is like RunModTasks but stops its commands when ctx is done
+func (g *GoRunner) RunModTasksContext(ctx context.Context) error {
+	ctx, span := g.start(ctx, "GoRunner.RunModTasks")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "mod", "📦 Running Go module maintenance (tidy & verify)...")
 
 	commands := [][]string{
 		{"mod", "tidy"},
@@ -87,55 +286,119 @@ func (g *GoRunner) RunModTasks() error {
 	}
 
 	for _, args := range commands {
-		slog.Info("🔧 Executing", "command", fmt.Sprintf("go %s", strings.Join(args, " ")))
-		if err := g.executor.Run(context.Background(), "go", false, args...); err != nil {
-			return fmt.Errorf("failed to run 'go %s': %w", strings.Join(args, " "), err)
+		logx.FromContext(ctx).InfoContext(ctx, "🔧 Executing", "command", fmt.Sprintf("go %s", strings.Join(args, " ")))
+		if err := g.executor.Run(ctx, bin, false, args...); err != nil {
+			return span.Fail(step.Fail(fmt.Errorf("failed to run 'go %s': %w", strings.Join(args, " "), err)))
 		}
 	}
-	slog.Info("✅ Module maintenance completed successfully", "duration", time.Since(start))
+	step.Done("✅ Module maintenance completed successfully")
 	return nil
 }
 
 // Run runs go mod tidy
 func (g *GoRunner) Run() error {
-	slog.Info("🧪 Running Go Mod Tidy...")
+	return g.RunContext(execx.Background())
+}
+
+// RunContext is like Run but stops its commands when ctx is done
+func (g *GoRunner) RunContext(ctx context.Context) error {
+	ctx, span := g.start(ctx, "GoRunner.Run")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "mod tidy", "🧪 Running Go Mod Tidy...")
 	defaultArgs := []string{"mod", "tidy"}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "go", false, defaultArgs...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, defaultArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Tests passed", "duration", time.Since(start))
+	step.Done("✅ Tests passed")
 	return nil
 }
 
 // Package-level convenience functions for backward compatibility
-var defaultRunner = NewGoRunner()
+var (
+	defaultExecutor execx.Executor = execx.NewDefaultExecutor()
+	defaultOptions  []Option
+	defaultRunner   = newDefaultRunner(defaultExecutor)
+)
+
+// newDefaultRunner creates the runner of the package-level functions.
+// Like NewGoRunner, it verifies tools before first use unless commands are only printed.
+func newDefaultRunner(executor execx.Executor, opts ...Option) *GoRunner {
+	if !execx.IsDryRun(executor) {
+		opts = append([]Option{WithToolCheck(nil)}, opts...)
+	}
+	return NewGoRunnerWithExecutor(executor, opts...)
+}
+
+// SetDefaultExecutor replaces the executor used by the package-level functions,
+// e.g. with execx.NewDryRun() to print commands for the rest of a mage run
+func SetDefaultExecutor(executor execx.Executor) {
+	defaultExecutor = executor
+	defaultRunner = newDefaultRunner(executor, defaultOptions...)
+}
+
+// SetDefaultOptions configures the runner used by the package-level functions,
+// e.g. with WithProfile to apply an environment profile
+func SetDefaultOptions(opts ...Option) {
+	defaultOptions = opts
+	defaultRunner = newDefaultRunner(defaultExecutor, opts...)
+}
 
 // RunTests runs Go tests with given arguments
 func RunTests(args ...string) error {
 	return defaultRunner.RunTests(args...)
 }
 
+// RunTestsContext is like RunTests but stops its commands when ctx is done
+func RunTestsContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunTestsContext(ctx, args...)
+}
+
 // RunLint runs golangci-lint with given arguments
 func RunLint(args ...string) error {
 	return defaultRunner.RunLint(args...)
 }
 
+// RunLintContext is like RunLint but stops its commands when ctx is done
+func RunLintContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunLintContext(ctx, args...)
+}
+
 // RunInstall installs Go packages
 func RunInstall(pkgs []string, args ...string) error {
 	return defaultRunner.RunInstall(pkgs, args...)
 }
 
+// RunInstallContext is like RunInstall but stops its commands when ctx is done
+func RunInstallContext(ctx context.Context, pkgs []string, args ...string) error {
+	return defaultRunner.RunInstallContext(ctx, pkgs, args...)
+}
+
 // RunModTasks runs `go mod tidy` and `go mod verify` sequentially
 func RunModTasks() error {
 	return defaultRunner.RunModTasks()
 }
 
+// RunModTasksContext is like RunModTasks but stops its commands when ctx is done
+func RunModTasksContext(ctx context.Context) error {
+	return defaultRunner.RunModTasksContext(ctx)
+}
+
 // Run runs go mod tidy
 func Run() error {
 	return defaultRunner.Run()
 }
 
+// RunContext is like Run but stops its commands when ctx is done
+func RunContext(ctx context.Context) error {
+	return defaultRunner.RunContext(ctx)
+}
+
 type BuildOptions struct {
 	Binary         string
 	Version        string
@@ -144,10 +407,17 @@ type BuildOptions struct {
 	Debug          bool
 	Packages       []string
 	DestinationDir string // NEW
+	Dir            string // Working directory for the build, e.g. a module in a monorepo
 }
 
-// RunBuild builds a Go binary with the given options
+// RunBuild builds a Go binary with the given options. With the default
+// executor, it is skipped when nothing changed since the last build.
 func (g *GoRunner) RunBuild(opts BuildOptions) error {
+	return g.RunBuildContext(execx.Background(), opts)
+}
+
+// RunBuildContext is like RunBuild but stops its commands when ctx is done
+func (g *GoRunner) RunBuildContext(ctx context.Context, opts BuildOptions) error {
 	if opts.Binary == "" {
 		return fmt.Errorf("binary name is required")
 	}
@@ -160,15 +430,21 @@ func (g *GoRunner) RunBuild(opts BuildOptions) error {
 		destDir = "dist/binaries"
 	}
 
-	slog.Info("🏗️ Building Go binary...",
+	ctx, span := g.start(ctx, "GoRunner.RunBuild")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "build", "🏗️ Building Go binary...",
 		"binary", opts.Binary,
 		"os", opts.OS,
 		"arch", opts.Arch,
 		"debug", opts.Debug,
 	)
 
-	start := time.Now()
-
 	// ---- ldflags ----
 	ldflags := fmt.Sprintf("-X main.version=%s", opts.Version)
 	if !opts.Debug {
@@ -178,17 +454,28 @@ func (g *GoRunner) RunBuild(opts BuildOptions) error {
 	// ---- output path ----
 	outDir := filepath.Join(destDir, opts.OS+"_"+opts.Arch)
 	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		return err
+		return span.Fail(step.Fail(err))
 	}
 
-	outPath := filepath.Join(outDir, opts.Binary)
+	outPath, err := filepath.Abs(filepath.Join(outDir, opts.Binary))
+	if err != nil {
+		return span.Fail(step.Fail(err))
+	}
+
+	if g.locking {
+		lock, err := lockx.Acquire(ctx, lockx.Key("go", "build", filepath.Dir(outPath)), g.lockOpts...)
+		if err != nil {
+			return span.Fail(step.Fail(err))
+		}
+		defer func() {
+			if err := lock.Release(); err != nil {
+				logx.FromContext(ctx).Warn("⚠️ Failed to release lock", "dir", filepath.Dir(outPath), "err", err)
+			}
+		}()
+	}
 
 	// ---- go build args ----
 	buildArgs := []string{
-		"GOOS=" + opts.OS,
-		"GOARCH=" + opts.Arch,
-		"CGO_ENABLED=0",
-		"go",
 		"build",
 		"-ldflags", ldflags,
 		"-o", outPath,
@@ -196,68 +483,166 @@ func (g *GoRunner) RunBuild(opts BuildOptions) error {
 	buildArgs = append(buildArgs, opts.Packages...)
 
 	// ---- runtime-only env execution ----
-	if err := g.executor.Run(
-		context.Background(),
-		"env",
-		false,
-		buildArgs...,
-	); err != nil {
-		return err
-	}
-
-	slog.Info("✅ Build completed",
-		"output", outPath,
-		"duration", time.Since(start),
+	ctx = execx.WithOptions(ctx,
+		execx.Dir(opts.Dir),
+		execx.Env(
+			"GOOS="+opts.OS,
+			"GOARCH="+opts.Arch,
+			"CGO_ENABLED=0",
+		),
 	)
+	if cache, ok := g.buildCache(ctx, bin, opts.Dir, outPath); ok {
+		ctx = execx.WithOptions(ctx, execx.Cached(cache))
+	}
+	if err := g.executor.Run(ctx, bin, false, buildArgs...); err != nil {
+		return span.Fail(step.Fail(err))
+	}
+
+	step.Done("✅ Build completed", "output", outPath)
 
 	return nil
 }
 
+// buildSettings are the `go env` settings a binary depends on besides its sources
+var buildSettings = []string{
+	"GOVERSION", "GOOS", "GOARCH", "CGO_ENABLED", "GOFLAGS", "GOEXPERIMENT", "GOWORK",
+	"GOAMD64", "GOARM", "GOARM64", "GO386", "GOMIPS", "GOPPC64", "GORISCV64",
+	"CC", "CXX", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS",
+}
+
+// BuildSettings returns the `go env` settings of the go command at bin that
+// a binary depends on besides its sources, including the toolchain version.
+// They are read with executor in the environment of ctx, so the values from
+// the go env file count too.
+func BuildSettings(ctx context.Context, executor execx.Executor, bin string) (map[string]string, error) {
+	capturer, ok := executor.(execx.Capturer)
+	if !ok {
+		return nil, fmt.Errorf("executor %T does not support capturing output", executor)
+	}
+	ctx = execx.WithOptions(ctx, execx.Stdin(nil), execx.StdinFrom(execx.NullStdin()))
+	res, err := capturer.Capture(ctx, bin, false, append([]string{"env", "-json"}, buildSettings...)...)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]string
+	if err := json.Unmarshal(res.Stdout, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse go env output: %w", err)
+	}
+	return settings, nil
+}
+
+// buildCache lets the default executor skip a build whose sources, embedded
+// files, build settings and binary are unchanged. The build is not cached
+// when its settings cannot be read.
+func (g *GoRunner) buildCache(ctx context.Context, bin, dir, outPath string) (execx.CacheSpec, bool) {
+	if execx.IsDryRun(g.executor) {
+		return execx.CacheSpec{}, false
+	}
+	settings, err := BuildSettings(ctx, g.executor, bin)
+	if err != nil {
+		logx.FromContext(ctx).DebugContext(ctx, "Build not cached, cannot read its settings", "err", err)
+		return execx.CacheSpec{}, false
+	}
+	return execx.CacheSpec{
+		Inputs:  SourceInputs(dir),
+		Keys:    settings,
+		Outputs: []string{outPath},
+	}, true
+}
+
 // RunTestsWithCoverage runs Go tests with coverage
 func (g *GoRunner) RunTestsWithCoverage(args ...string) error {
-	slog.Info("🧪 Running tests with coverage...")
+	return g.RunTestsWithCoverageContext(execx.Background(), args...)
+}
+
+// RunTestsWithCoverageContext is like RunTestsWithCoverage but stops its commands when ctx is done
+func (g *GoRunner) RunTestsWithCoverageContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunTestsWithCoverage")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "test", "🧪 Running tests with coverage...")
 	defaultArgs := []string{"test", "-cover", "-coverprofile=coverage.out", "./..."}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "go", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Tests with coverage passed", "duration", time.Since(start))
+	step.Done("✅ Tests with coverage passed")
 	return nil
 }
 
 // RunVet runs go vet
 func (g *GoRunner) RunVet(args ...string) error {
-	slog.Info("🔍 Running go vet...")
+	return g.RunVetContext(execx.Background(), args...)
+}
+
+// RunVetContext is like RunVet but stops its commands when ctx is done
+func (g *GoRunner) RunVetContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunVet")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "go")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "go", "vet", "🔍 Running go vet...")
 	defaultArgs := []string{"vet", "./..."}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "go", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Go vet passed", "duration", time.Since(start))
+	step.Done("✅ Go vet passed")
 	return nil
 }
 
 // RunFormat formats Go files using gofmt
 func (g *GoRunner) RunFormat(args ...string) error {
-	slog.Info("✨ Formatting Go files...")
+	return g.RunFormatContext(execx.Background(), args...)
+}
+
+// RunFormatContext is like RunFormat but stops its commands when ctx is done
+func (g *GoRunner) RunFormatContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunFormat")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "gofmt")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "gofmt", "-w", "✨ Formatting Go files...")
 	defaultArgs := []string{"-w", "."}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "gofmt", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Formatting complete", "duration", time.Since(start))
+	step.Done("✅ Formatting complete")
 	return nil
 }
 
 // RunFormatImports formats Go imports using goimports
 func (g *GoRunner) RunFormatImports(args ...string) error {
-	slog.Info("✨ Formatting Go imports...")
+	return g.RunFormatImportsContext(execx.Background(), args...)
+}
+
+// RunFormatImportsContext is like RunFormatImports but stops its commands when ctx is done
+func (g *GoRunner) RunFormatImportsContext(ctx context.Context, args ...string) error {
+	ctx, span := g.start(ctx, "GoRunner.RunFormatImports")
+	defer span.Finish()
+
+	bin, err := g.tools.Command(ctx, "goimports")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "goimports", "-w", "✨ Formatting Go imports...")
 	defaultArgs := []string{"-w", "."}
-	start := time.Now()
-	if err := g.executor.Run(context.Background(), "goimports", false, append(defaultArgs, args...)...); err != nil {
-		return err
+	if err := g.executor.Run(ctx, bin, false, append(defaultArgs, args...)...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
-	slog.Info("✅ Import formatting complete", "duration", time.Since(start))
+	step.Done("✅ Import formatting complete")
 	return nil
 }
 
@@ -266,22 +651,47 @@ func RunBuild(opts BuildOptions) error {
 	return defaultRunner.RunBuild(opts)
 }
 
+// RunBuildContext is like RunBuild but stops its commands when ctx is done
+func RunBuildContext(ctx context.Context, opts BuildOptions) error {
+	return defaultRunner.RunBuildContext(ctx, opts)
+}
+
 // RunTestsWithCoverage runs Go tests with coverage (package-level convenience function)
 func RunTestsWithCoverage(args ...string) error {
 	return defaultRunner.RunTestsWithCoverage(args...)
 }
 
+// RunTestsWithCoverageContext is like RunTestsWithCoverage but stops its commands when ctx is done
+func RunTestsWithCoverageContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunTestsWithCoverageContext(ctx, args...)
+}
+
 // RunVet runs go vet (package-level convenience function)
 func RunVet(args ...string) error {
 	return defaultRunner.RunVet(args...)
 }
 
+// RunVetContext is like RunVet but stops its commands when ctx is done
+func RunVetContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunVetContext(ctx, args...)
+}
+
 // RunFormat formats Go files (package-level convenience function)
 func RunFormat(args ...string) error {
 	return defaultRunner.RunFormat(args...)
 }
 
+// RunFormatContext is like RunFormat but stops its commands when ctx is done
+func RunFormatContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunFormatContext(ctx, args...)
+}
+
 // RunFormatImports formats Go imports (package-level convenience function)
 func RunFormatImports(args ...string) error {
 	return defaultRunner.RunFormatImports(args...)
 }
+
+// RunFormatImportsContext is like RunFormatImports but stops its commands when ctx is done
+func RunFormatImportsContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RunFormatImportsContext(ctx, args...)
+}
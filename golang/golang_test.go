@@ -0,0 +1,147 @@
+package golang
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx/execxtest"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
+)
+
+// newRunner returns a GoRunner running its commands with fake and logging nowhere
+func newRunner(t *testing.T, fake *execxtest.Fake, opts ...Option) *GoRunner {
+	t.Helper()
+	t.Setenv(toolx.BinDirEnv, t.TempDir())
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return NewGoRunnerWithExecutor(fake, opts...)
+}
+
+func TestRunBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		opts BuildOptions
+		want func(outPath string) []string
+	}{
+		{
+			name: "release",
+			opts: BuildOptions{Binary: "app", Version: "v1.2.3", OS: "linux", Arch: "amd64"},
+			want: func(outPath string) []string {
+				return []string{"build", "-ldflags", "-X main.version=v1.2.3 -s -w", "-o", outPath, "."}
+			},
+		},
+		{
+			name: "debug with packages",
+			opts: BuildOptions{Binary: "app", Version: "dev", OS: "darwin", Arch: "arm64", Debug: true, Packages: []string{"./cmd/app"}},
+			want: func(outPath string) []string {
+				return []string{"build", "-ldflags", "-X main.version=dev", "-o", outPath, "./cmd/app"}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.DestinationDir = t.TempDir()
+			outPath := filepath.Join(tt.opts.DestinationDir, tt.opts.OS+"_"+tt.opts.Arch, tt.opts.Binary)
+
+			fake := execxtest.New(t).InOrder()
+			fake.Expect("go", append([]string{"env", "-json"}, buildSettings...)...).
+				Env("GOOS="+tt.opts.OS, "GOARCH="+tt.opts.Arch, "CGO_ENABLED=0").
+				Stdout(`{"GOVERSION": "go1.25.0", "GOOS": "` + tt.opts.OS + `"}`)
+			fake.Expect("go", tt.want(outPath)...).
+				Env("GOOS="+tt.opts.OS, "GOARCH="+tt.opts.Arch, "CGO_ENABLED=0")
+			if err := newRunner(t, fake).RunBuildContext(context.Background(), tt.opts); err != nil {
+				t.Errorf("RunBuildContext() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRunBuildWithoutSettings(t *testing.T) {
+	opts := BuildOptions{Binary: "app", Version: "dev", OS: "linux", Arch: "amd64", DestinationDir: t.TempDir()}
+	outPath := filepath.Join(opts.DestinationDir, "linux_amd64", "app")
+
+	// The build still runs, only without cache
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("go", append([]string{"env", "-json"}, buildSettings...)...).ExitCode(1)
+	fake.Expect("go", "build", "-ldflags", "-X main.version=dev -s -w", "-o", outPath, ".")
+	if err := newRunner(t, fake).RunBuildContext(context.Background(), opts); err != nil {
+		t.Errorf("RunBuildContext() error = %v", err)
+	}
+}
+
+func TestRunBuildRequiresBinary(t *testing.T) {
+	err := newRunner(t, execxtest.New(t)).RunBuildContext(context.Background(), BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "binary name is required") {
+		t.Errorf("RunBuildContext() error = %v, want binary name is required", err)
+	}
+}
+
+func TestRunModTasks(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("go", "mod", "tidy")
+	fake.Expect("go", "mod", "verify").Stderr("verifying module: checksum mismatch\n").ExitCode(1)
+
+	err := newRunner(t, fake).RunModTasksContext(context.Background())
+	var exitErr *execxtest.ExitError
+	if !errors.As(err, &exitErr) || !strings.Contains(err.Error(), "failed to run 'go mod verify'") {
+		t.Errorf("RunModTasksContext() error = %v, want go mod verify to fail", err)
+	}
+}
+
+func TestRunInstall(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("go", "install", "example.com/a@latest", "-v")
+	fake.Expect("go", "install", "example.com/b@latest", "-v").ExitCode(1)
+
+	err := newRunner(t, fake).RunInstallContext(context.Background(), []string{"example.com/a@latest", "example.com/b@latest"}, "-v")
+	if err == nil || !strings.Contains(err.Error(), "failed to install example.com/b@latest") {
+		t.Errorf("RunInstallContext() error = %v, want the second package to fail", err)
+	}
+
+	if err := newRunner(t, execxtest.New(t)).RunInstallContext(context.Background(), nil); err == nil {
+		t.Error("RunInstallContext() without packages error = nil")
+	}
+}
+
+func TestRunTestsAndLint(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("go", "test", "./...", "-race")
+	fake.Expect("golangci-lint", "run", "--timeout=5m")
+
+	g := newRunner(t, fake, WithBinary("golangci-lint", "/opt/lint/golangci-lint"))
+	if err := g.RunTestsContext(context.Background(), "-race"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RunLintContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		retry   bool
+	}{
+		{"go", []string{"install", "example.com/tool@v1"}, true},
+		{"go", []string{"get", "example.com/lib"}, true},
+		{"go", []string{"mod", "download"}, true},
+		{"go", []string{"mod", "tidy"}, true},
+		{"go", []string{"mod", "verify"}, false},
+		{"go", []string{"build", "."}, false},
+		{"go", nil, false},
+		{"/proj/bin/go", []string{"mod", "download"}, true},
+		{`C:\Go\bin\go.exe`, []string{"install", "x"}, filepath.Separator == '\\'},
+		{"golangci-lint", []string{"install"}, false},
+	}
+	for _, tt := range tests {
+		policy := RetryPolicyFor(tt.command, tt.args)
+		if got := policy.Attempts > 1; got != tt.retry {
+			t.Errorf("RetryPolicyFor(%q, %q) retries = %v, want %v", tt.command, tt.args, got, tt.retry)
+		}
+	}
+}
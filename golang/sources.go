@@ -0,0 +1,240 @@
+package golang
+
+import (
+	"bufio"
+	"cmp"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sourcePatterns are the files of a module a build reads, besides embedded ones
+var sourcePatterns = []string{
+	"go.mod", "go.sum", "go.work", "go.work.sum", "vendor/modules.txt",
+	"**/*.go", "**/*.s", "**/*.syso",
+}
+
+// modulePatterns are the files a build reads from another module it uses locally
+var modulePatterns = []string{"go.mod", "go.sum", "**/*.go", "**/*.s", "**/*.syso"}
+
+// SourceInputs returns the cache inputs of a build of the module in dir: its
+// go.mod, go.sum and sources, plus the files its //go:embed directives name.
+// Modules used from other directories, by the go.work file or by replace
+// directives with a local path, are inputs in the same way.
+// The globs are relative to dir, so build outputs below it are not hashed.
+func SourceInputs(dir string) []string {
+	dir = cmp.Or(dir, ".")
+	inputs := slices.Clone(sourcePatterns)
+	inputs = append(inputs, embedPatterns(dir)...)
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return inputs
+	}
+	// go.work in dir is a source pattern already
+	if work := workFile(abs); work != "" && filepath.Dir(work) != abs {
+		if rel, err := filepath.Rel(abs, work); err == nil {
+			inputs = append(inputs, filepath.ToSlash(rel), filepath.ToSlash(rel)+".sum")
+		}
+	}
+	for _, mod := range localModules(abs) {
+		patterns := append(slices.Clone(modulePatterns), embedPatterns(filepath.Join(dir, mod))...)
+		for _, pattern := range patterns {
+			inputs = append(inputs, path.Join(mod, pattern))
+		}
+	}
+	return inputs
+}
+
+// localModules returns the directories of the modules the build in the
+// absolute dir uses from the file system, relative to dir: the go.work use
+// directives and the replace directives with a local path of go.work and of
+// dir's go.mod
+func localModules(dir string) []string {
+	var dirs []string
+	add := func(base, target string) {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(base, target)
+		}
+		rel, err := filepath.Rel(dir, target)
+		if err != nil || rel == "." {
+			return
+		}
+		if rel = filepath.ToSlash(rel); !slices.Contains(dirs, rel) {
+			dirs = append(dirs, rel)
+		}
+	}
+
+	for _, target := range localReplacements(filepath.Join(dir, "go.mod")) {
+		add(dir, target)
+	}
+	if work := workFile(dir); work != "" {
+		base := filepath.Dir(work)
+		for _, args := range directives(work, "use") {
+			add(base, args[0])
+		}
+		for _, target := range localReplacements(work) {
+			add(base, target)
+		}
+	}
+	return dirs
+}
+
+// workFile returns the absolute path of the go.work file the go command uses
+// in the absolute dir, if any: the one GOWORK names, or the first one found
+// in dir and its parents
+func workFile(dir string) string {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+	default:
+		abs, _ := filepath.Abs(gowork)
+		return abs
+	}
+	for {
+		file := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// localReplacements returns the targets of the replace directives in the
+// go.mod or go.work file that are directories rather than module versions
+func localReplacements(file string) []string {
+	var targets []string
+	for _, args := range directives(file, "replace") {
+		i := slices.Index(args, "=>")
+		if i < 0 || i+1 >= len(args) {
+			continue
+		}
+		target := args[i+1]
+		if target == "." || target == ".." || strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") ||
+			strings.HasPrefix(target, `.\`) || strings.HasPrefix(target, `..\`) || filepath.IsAbs(target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// directives returns the arguments of every verb directive in a go.mod or
+// go.work file, whether written on one line or in a parenthesized block
+func directives(file, verb string) [][]string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var list [][]string
+	inBlock := false
+	for line := range strings.Lines(string(data)) {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			if unquoted, err := strconv.Unquote(f); err == nil {
+				fields[i] = unquoted
+			}
+		}
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			list = append(list, fields)
+		case fields[0] != verb:
+		case len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+		case len(fields) > 1:
+			list = append(list, fields[1:])
+		}
+	}
+	return list
+}
+
+// embedPatterns returns the //go:embed patterns of the non-test sources below
+// dir, relative to dir. Directories the go command ignores are skipped.
+func embedPatterns(dir string) []string {
+	dir = cmp.Or(dir, ".")
+	var patterns []string
+	_ = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if file != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(file))
+		if err != nil {
+			return nil
+		}
+		for _, pattern := range fileEmbeds(file) {
+			patterns = append(patterns, path.Join(filepath.ToSlash(rel), pattern))
+		}
+		return nil
+	})
+	return patterns
+}
+
+// fileEmbeds returns the patterns of the //go:embed directives in file
+func fileEmbeds(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		args, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "//go:embed")
+		if !ok || args != "" && args[0] != ' ' && args[0] != '\t' {
+			continue
+		}
+		for _, pattern := range embedArgs(args) {
+			patterns = append(patterns, strings.TrimPrefix(pattern, "all:"))
+		}
+	}
+	return patterns
+}
+
+// embedArgs splits the arguments of a //go:embed directive, which may be Go
+// string literals to allow spaces
+func embedArgs(args string) []string {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if args[0] == '"' || args[0] == '`' {
+			quoted, err := strconv.QuotedPrefix(args)
+			if err != nil {
+				return list
+			}
+			arg, _ := strconv.Unquote(quoted)
+			list = append(list, arg)
+			args = args[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(args, " \t")
+		if end < 0 {
+			end = len(args)
+		}
+		list = append(list, args[:end])
+		args = args[end:]
+	}
+	return list
+}
@@ -0,0 +1,98 @@
+package golang
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEmbedArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{" static", []string{"static"}},
+		{" a.txt  b/*.html\tc", []string{"a.txt", "b/*.html", "c"}},
+		{` "with space.txt" plain`, []string{"with space.txt", "plain"}},
+		{" `raw name.txt`", []string{"raw name.txt"}},
+		{` "esc\"aped"`, []string{`esc"aped`}},
+		{` "unterminated`, nil},
+	}
+	for _, tt := range tests {
+		if got := embedArgs(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("embedArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// writeFiles creates files below dir from their slash-separated names
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSourceInputs(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                      "module example.com/app\n",
+		"main.go":                     "package main\n\n//go:embed version.txt\nvar version string\n",
+		"web/web.go":                  "package web\n\n//go:embed static/*.html \"my file.txt\" all:templates\nvar files embed.FS\n",
+		"web/web_test.go":             "package web\n\n//go:embed testfixtures\nvar fixtures embed.FS\n",
+		"web/notembed.go":             "package web\n\n//go:embedded is not a directive\n",
+		"testdata/x.go":               "package x\n\n//go:embed ignored\nvar x string\n",
+		"_tools/tools.go":             "package tools\n\n//go:embed ignored\nvar x string\n",
+		"dist/binaries/linux_amd64/x": "binary",
+	}
+	writeFiles(t, dir, files)
+
+	got := SourceInputs(dir)
+	want := append(slices.Clone(sourcePatterns), "version.txt", "web/static/*.html", "web/my file.txt", "web/templates")
+	if !slices.Equal(got, want) {
+		t.Errorf("SourceInputs() = %q, want %q", got, want)
+	}
+}
+
+func TestSourceInputsLocalModules(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.work": "go 1.25\n\nuse (\n\t./app // the binary\n\t./lib\n)\n",
+		"app/go.mod": "module example.com/app\n\nrequire example.com/util v1.0.0\n\n" +
+			"replace example.com/util => ../util\n\nreplace (\n\texample.com/x v1.0.0 => example.com/y v1.1.0\n)\n",
+		"lib/lib.go":   "package lib\n\n//go:embed schema.json\nvar schema string\n",
+		"util/util.go": "package util\n",
+	})
+
+	got := SourceInputs(filepath.Join(root, "app"))
+	for _, want := range []string{
+		"../go.work", "../go.work.sum",
+		"../util/go.mod", "../util/**/*.go",
+		"../lib/go.mod", "../lib/**/*.go", "../lib/schema.json",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("SourceInputs() lacks %q: %q", want, got)
+		}
+	}
+	for _, input := range got {
+		if strings.Contains(input, "example.com") || strings.HasPrefix(input, "../app") {
+			t.Errorf("SourceInputs() has %q", input)
+		}
+	}
+
+	t.Setenv("GOWORK", "off")
+	if got := SourceInputs(filepath.Join(root, "app")); slices.Contains(got, "../lib/go.mod") {
+		t.Errorf("SourceInputs() with GOWORK=off uses the workspace: %q", got)
+	}
+}
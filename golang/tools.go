@@ -0,0 +1,200 @@
+package golang
+
+import (
+	"context"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/lockx"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
+)
+
+// ChecksumMismatchError is returned when an installed module does not match
+// the go.sum hash recorded in the lockfile for the same version
+type ChecksumMismatchError struct {
+	Tool     string
+	Version  string
+	Recorded string
+	Found    string
+}
+
+// Error implements the error interface
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s %s: module checksum %s does not match %s recorded in the lockfile",
+		e.Tool, e.Version, e.Found, e.Recorded)
+}
+
+// InstallTools installs the tools pinned in the manifest at manifestPath into
+// toolx.BinDir with `go install <package>@<version>`, and records them with
+// their checksums in the lockfile at lockPath. Tools whose installed binary
+// matches the lockfile are skipped. Runners resolve binaries from
+// toolx.BinDir before PATH, so the pinned versions are used from then on.
+func (g *GoRunner) InstallTools(manifestPath, lockPath string) error {
+	return g.InstallToolsContext(execx.Background(), manifestPath, lockPath)
+}
+
+// InstallToolsContext is like InstallTools but stops its commands when ctx is done
+func (g *GoRunner) InstallToolsContext(ctx context.Context, manifestPath, lockPath string) error {
+	manifest, err := toolx.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	lock, err := toolx.LoadLockfile(lockPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := g.start(ctx, "GoRunner.InstallTools")
+	defer span.Finish()
+
+	binDir := toolx.BinDir()
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		return span.Fail(err)
+	}
+
+	if g.locking {
+		l, err := lockx.Acquire(ctx, lockx.Key("go", "tools", binDir), g.lockOpts...)
+		if err != nil {
+			return span.Fail(err)
+		}
+		defer func() {
+			if err := l.Release(); err != nil {
+				logx.FromContext(ctx).Warn("⚠️ Failed to release lock", "dir", binDir, "err", err)
+			}
+		}()
+	}
+
+	step := logx.Begin(ctx, "go", "install", "🧰 Installing pinned tools...", "manifest", manifestPath, "bin", binDir)
+
+	installed := &toolx.Lockfile{}
+	dryRun := execx.IsDryRun(g.executor)
+	ctx = execx.WithOptions(ctx, execx.Env("GOBIN="+binDir))
+	for _, tool := range manifest.Tools {
+		name := tool.BinaryName()
+		path := toolx.BinPath(name)
+		locked := lock.Lookup(name)
+
+		if checksum, ok := upToDate(tool, locked, path); ok {
+			logx.FromContext(ctx).InfoContext(ctx, "⏭️ Tool up to date", "name", name, "version", tool.Version)
+			entry := *locked
+			entry.SHA256 = withChecksum(entry.SHA256, checksum)
+			installed.Put(entry)
+			continue
+		}
+
+		if err := g.RunInstallContext(ctx, []string{tool.Package + "@" + tool.Version}); err != nil {
+			return span.Fail(step.Fail(err))
+		}
+		if dryRun {
+			// Nothing was installed to inspect
+			continue
+		}
+
+		entry, err := lockEntry(tool, path)
+		if err != nil {
+			return span.Fail(step.Fail(fmt.Errorf("failed to inspect %s: %w", name, err)))
+		}
+		if locked != nil && locked.Package == entry.Package && locked.Version == entry.Version {
+			if locked.Sum != "" && locked.Sum != entry.Sum {
+				_ = os.Remove(path)
+				return span.Fail(step.Fail(&ChecksumMismatchError{Tool: name, Version: entry.Version, Recorded: locked.Sum, Found: entry.Sum}))
+			}
+			// Keep the checksums other platforms recorded for the same module
+			entry.SHA256 = withChecksum(locked.SHA256, entry.SHA256[toolx.Platform()])
+		}
+		installed.Put(entry)
+	}
+
+	if !dryRun {
+		if err := installed.Save(lockPath); err != nil {
+			return span.Fail(step.Fail(err))
+		}
+	}
+
+	step.Done("✅ Tools installed", "count", len(manifest.Tools))
+	return nil
+}
+
+// upToDate reports whether the binary at path is the locked version of tool.
+// It returns the binary's checksum, to be recorded for this platform.
+func upToDate(tool toolx.ManifestTool, locked *toolx.LockedTool, path string) (string, bool) {
+	if locked == nil || locked.Package != tool.Package || locked.Version != tool.Version {
+		return "", false
+	}
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return "", false
+	}
+	if want, ok := locked.SHA256[toolx.Platform()]; ok {
+		return checksum, checksum == want
+	}
+	// Locked on another platform: trust a binary built from the same module
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return checksum, info.Main.Version == tool.Version && (locked.Sum == "" || info.Main.Sum == locked.Sum)
+}
+
+// lockEntry describes the freshly installed binary of tool
+func lockEntry(tool toolx.ManifestTool, path string) (toolx.LockedTool, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return toolx.LockedTool{}, err
+	}
+	if info.Main.Version != tool.Version {
+		return toolx.LockedTool{}, fmt.Errorf("installed version %s, want %s", info.Main.Version, tool.Version)
+	}
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return toolx.LockedTool{}, err
+	}
+	return toolx.LockedTool{
+		Name:    tool.BinaryName(),
+		Package: tool.Package,
+		Version: tool.Version,
+		Sum:     info.Main.Sum,
+		SHA256:  withChecksum(nil, checksum),
+	}, nil
+}
+
+// withChecksum records checksum for the current platform
+func withChecksum(checksums map[string]string, checksum string) map[string]string {
+	merged := map[string]string{toolx.Platform(): checksum}
+	for platform, c := range checksums {
+		if _, ok := merged[platform]; !ok {
+			merged[platform] = c
+		}
+	}
+	return merged
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// InstallTools installs the tools pinned in a manifest into toolx.BinDir
+func InstallTools(manifestPath, lockPath string) error {
+	return defaultRunner.InstallTools(manifestPath, lockPath)
+}
+
+// InstallToolsContext is like InstallTools but stops its commands when ctx is done
+func InstallToolsContext(ctx context.Context, manifestPath, lockPath string) error {
+	return defaultRunner.InstallToolsContext(ctx, manifestPath, lockPath)
+}
@@ -0,0 +1,39 @@
+package golang
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
+)
+
+func TestInstallToolsDryRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(toolx.BinDirEnv, filepath.Join(dir, "bin"))
+	manifest := filepath.Join(dir, "tools.json")
+	lockPath := filepath.Join(dir, "tools.lock.json")
+	if err := os.WriteFile(manifest, []byte(`{"tools": [{"package": "example.com/cmd/tool", "version": "v1.2.3"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	// Not DryRunEnv: the executor itself only prints commands
+	executor := execx.NewRetry(execx.NewDryRunWithWriter(&out), execx.DefaultRetryPolicy)
+	g := NewGoRunnerWithExecutor(executor, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err := g.InstallToolsContext(context.Background(), manifest, lockPath); err != nil {
+		t.Fatalf("InstallToolsContext() error = %v, want the missing binary not inspected", err)
+	}
+	if !strings.Contains(out.String(), "go install example.com/cmd/tool@v1.2.3") {
+		t.Errorf("dry run printed %q, want the install command", out.String())
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lockfile was written in a dry run: %v", err)
+	}
+}
@@ -1,58 +1,138 @@
 package helmmagex
 
 import (
+	"context"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
 	"github.com/vinaycharlie01/go-mage-shared/helmx"
 )
 
 // Package-level convenience functions for backward compatibility
-var defaultRunner = helmx.NewHelmRunner()
+var (
+	defaultExecutor execx.Executor = execx.NewDefaultExecutor()
+	defaultOptions  []helmx.Option
+	defaultRunner   = newDefaultRunner(defaultExecutor)
+)
+
+// newDefaultRunner creates the runner of the package-level functions.
+// Like helmx.NewHelmRunner, it verifies tools before first use unless commands are only printed.
+func newDefaultRunner(executor execx.Executor, opts ...helmx.Option) *helmx.HelmRunner {
+	if !execx.IsDryRun(executor) {
+		opts = append([]helmx.Option{helmx.WithToolCheck(nil)}, opts...)
+	}
+	return helmx.NewHelmRunnerWithExecutor(executor, opts...)
+}
+
+// SetDefaultExecutor replaces the executor used by the package-level functions,
+// e.g. with execx.NewDryRun() to print commands for the rest of a mage run
+func SetDefaultExecutor(executor execx.Executor) {
+	defaultExecutor = executor
+	defaultRunner = newDefaultRunner(executor, defaultOptions...)
+}
+
+// SetDefaultOptions configures the runner used by the package-level functions,
+// e.g. with helmx.WithProfile to apply an environment profile
+func SetDefaultOptions(opts ...helmx.Option) {
+	defaultOptions = opts
+	defaultRunner = newDefaultRunner(defaultExecutor, opts...)
+}
 
 // Install installs a Helm chart
 func Install(opts helmx.InstallOptions) error {
 	return defaultRunner.Install(opts)
 }
 
+// InstallContext is like Install but stops its commands when ctx is done
+func InstallContext(ctx context.Context, opts helmx.InstallOptions) error {
+	return defaultRunner.InstallContext(ctx, opts)
+}
+
 // Upgrade upgrades a Helm release
 func Upgrade(opts helmx.UpgradeOptions) error {
 	return defaultRunner.Upgrade(opts)
 }
 
+// UpgradeContext is like Upgrade but stops its commands when ctx is done
+func UpgradeContext(ctx context.Context, opts helmx.UpgradeOptions) error {
+	return defaultRunner.UpgradeContext(ctx, opts)
+}
+
 // Uninstall uninstalls a Helm release
 func Uninstall(releaseName, namespace string, args ...string) error {
 	return defaultRunner.Uninstall(releaseName, namespace, args...)
 }
 
+// UninstallContext is like Uninstall but stops its commands when ctx is done
+func UninstallContext(ctx context.Context, releaseName, namespace string, args ...string) error {
+	return defaultRunner.UninstallContext(ctx, releaseName, namespace, args...)
+}
+
 // List lists Helm releases
 func List(namespace string, args ...string) error {
 	return defaultRunner.List(namespace, args...)
 }
 
+// ListContext is like List but stops its commands when ctx is done
+func ListContext(ctx context.Context, namespace string, args ...string) error {
+	return defaultRunner.ListContext(ctx, namespace, args...)
+}
+
 // Status shows the status of a Helm release
 func Status(releaseName, namespace string, args ...string) error {
 	return defaultRunner.Status(releaseName, namespace, args...)
 }
 
+// StatusContext is like Status but stops its commands when ctx is done
+func StatusContext(ctx context.Context, releaseName, namespace string, args ...string) error {
+	return defaultRunner.StatusContext(ctx, releaseName, namespace, args...)
+}
+
 // Template renders chart templates locally
 func Template(releaseName, chart string, args ...string) error {
 	return defaultRunner.Template(releaseName, chart, args...)
 }
 
+// TemplateContext is like Template but stops its commands when ctx is done
+func TemplateContext(ctx context.Context, releaseName, chart string, args ...string) error {
+	return defaultRunner.TemplateContext(ctx, releaseName, chart, args...)
+}
+
 // Lint runs helm lint on a chart
 func Lint(chart string, args ...string) error {
 	return defaultRunner.Lint(chart, args...)
 }
 
+// LintContext is like Lint but stops its commands when ctx is done
+func LintContext(ctx context.Context, chart string, args ...string) error {
+	return defaultRunner.LintContext(ctx, chart, args...)
+}
+
 // Package packages a chart directory into a chart archive
 func Package(chart string, args ...string) error {
 	return defaultRunner.Package(chart, args...)
 }
 
+// PackageContext is like Package but stops its commands when ctx is done
+func PackageContext(ctx context.Context, chart string, args ...string) error {
+	return defaultRunner.PackageContext(ctx, chart, args...)
+}
+
 // RepoAdd adds a chart repository
 func RepoAdd(name, url string, args ...string) error {
 	return defaultRunner.RepoAdd(name, url, args...)
 }
 
+// RepoAddContext is like RepoAdd but stops its commands when ctx is done
+func RepoAddContext(ctx context.Context, name, url string, args ...string) error {
+	return defaultRunner.RepoAddContext(ctx, name, url, args...)
+}
+
 // RepoUpdate updates chart repositories
 func RepoUpdate(args ...string) error {
 	return defaultRunner.RepoUpdate(args...)
 }
+
+// RepoUpdateContext is like RepoUpdate but stops its commands when ctx is done
+func RepoUpdateContext(ctx context.Context, args ...string) error {
+	return defaultRunner.RepoUpdateContext(ctx, args...)
+}
@@ -4,30 +4,234 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/vinaycharlie01/go-mage-shared/envx"
 	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/lockx"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
 )
 
 // HelmRunner handles Helm command execution with dependency injection
 type HelmRunner struct {
 	executor execx.Executor
+	tools    *toolx.Set
+	logger   *slog.Logger
+	passEnv  []string
+	profile  *envx.Profile
+	locking  bool
+	lockOpts []lockx.Option
 }
 
-// NewHelmRunner creates a new HelmRunner with the default executor
-func NewHelmRunner() *HelmRunner {
-	return &HelmRunner{
-		executor: execx.NewExec(),
+// Option configures a HelmRunner
+type Option func(*HelmRunner)
+
+// WithRetry retries subcommands that talk to chart repositories or registries
+// with the policies from RetryPolicyFor
+func WithRetry() Option {
+	return func(h *HelmRunner) {
+		h.executor = execx.NewRetryWithPolicies(h.executor, RetryPolicyFor)
+	}
+}
+
+// WithRedactor masks the secrets known to r in echoed commands,
+// streamed and captured output and errors of every command the runner executes.
+// Without it, execx.DefaultRedactor masks the values passed with --set that
+// look like passwords.
+func WithRedactor(r *execx.Redactor) Option {
+	return func(h *HelmRunner) {
+		h.executor = execx.NewChain(h.executor, execx.RedactionInterceptor(r))
+	}
+}
+
+// WithLocking serializes mutating operations across processes on this machine:
+// Install, Upgrade and Uninstall of the same release and namespace, and
+// repository changes. This prevents concurrent upgrades leaving a release
+// stuck in pending-upgrade.
+func WithLocking(opts ...lockx.Option) Option {
+	return func(h *HelmRunner) {
+		h.locking = true
+		h.lockOpts = opts
+	}
+}
+
+// Tools lists the binaries the runner executes and the versions it supports
+var Tools = []toolx.Requirement{
+	{Tool: "helm", Constraint: ">=3.0"},
+}
+
+// PassEnv lists the variables the runner's tools legitimately read from our
+// environment: where Helm keeps its configuration, repositories and registry
+// credentials. Hermetic commands inherit them, see execx.Hermetic.
+var PassEnv = []string{
+	"HELM_CACHE_HOME", "HELM_CONFIG_HOME", "HELM_DATA_HOME",
+	"HELM_REGISTRY_CONFIG", "HELM_REPOSITORY_CACHE", "HELM_REPOSITORY_CONFIG",
+	"DOCKER_CONFIG",
+}
+
+// WithPassEnv declares more variables the runner's commands inherit when they
+// run hermetic, e.g. "KUBECONFIG"
+func WithPassEnv(names ...string) Option {
+	return func(h *HelmRunner) {
+		h.passEnv = append(h.passEnv, names...)
+	}
+}
+
+// Variables of a profile HelmRunner takes option defaults from, see WithProfile
+const (
+	NamespaceVar = "HELM_NAMESPACE" // Namespace of a release when none is given
+	ValuesVar    = "HELM_VALUES"    // Comma-separated values files applied before the given ones
+)
+
+// WithProfile runs the runner's commands with the variables of p, e.g.
+// HELM_KUBECONTEXT, and takes the defaults of Install, Upgrade, Uninstall
+// and Status from its NamespaceVar and ValuesVar
+func WithProfile(p *envx.Profile) Option {
+	return func(h *HelmRunner) {
+		h.profile = p
+	}
+}
+
+// WithBinary runs tool from path instead of looking it up in PATH
+func WithBinary(tool, path string) Option {
+	return func(h *HelmRunner) {
+		h.tools.UsePath(tool, path)
+	}
+}
+
+// WithToolCheck verifies that each tool is installed and satisfies Tools
+// before it is first used, with toolx.DefaultRegistry when registry is nil
+func WithToolCheck(registry *toolx.Registry) Option {
+	return func(h *HelmRunner) {
+		if registry == nil {
+			registry = toolx.DefaultRegistry()
+		}
+		h.tools.VerifyWith(registry)
+	}
+}
+
+// WithoutToolCheck runs tools without verifying them first
+func WithoutToolCheck() Option {
+	return func(h *HelmRunner) {
+		h.tools.VerifyWith(nil)
+	}
+}
+
+// WithLogger reports the runner's progress, and the output of commands run
+// with streamToLog, to l instead of logx.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(h *HelmRunner) {
+		h.logger = l
 	}
 }
 
+// WithLogMode reports the runner's progress to stderr in mode, e.g. logx.Quiet
+func WithLogMode(mode logx.Mode) Option {
+	return WithLogger(logx.New(os.Stderr, mode))
+}
+
+// NewHelmRunner creates a new HelmRunner with the default executor.
+// Commands are only printed when execx.DryRunEnv is set; otherwise each tool
+// is verified with toolx.DefaultRegistry before it is first used.
+func NewHelmRunner(opts ...Option) *HelmRunner {
+	executor := execx.NewDefaultExecutor()
+	if !execx.IsDryRun(executor) {
+		opts = append([]Option{WithToolCheck(nil)}, opts...)
+	}
+	return NewHelmRunnerWithExecutor(executor, opts...)
+}
+
 // NewHelmRunnerWithExecutor creates a new HelmRunner with a custom executor
-func NewHelmRunnerWithExecutor(executor execx.Executor) *HelmRunner {
-	return &HelmRunner{
+func NewHelmRunnerWithExecutor(executor execx.Executor, opts ...Option) *HelmRunner {
+	h := &HelmRunner{
 		executor: executor,
+		tools:    toolx.NewSet(Tools...),
+		passEnv:  slices.Clone(PassEnv),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// start begins the span of a runner method, makes the runner's logger the
+// one its commands report to and gives them the variables they need.
+// Secrets are masked with execx.DefaultRedactor unless ctx sets a Redactor.
+func (h *HelmRunner) start(ctx context.Context, name string) (context.Context, *execx.Span) {
+	if h.logger != nil {
+		ctx = logx.NewContext(ctx, h.logger)
+	}
+	ctx = execx.WithOptions(ctx, execx.PassEnv(h.passEnv...), execx.DefaultRedaction())
+	ctx = h.profile.Context(ctx)
+	return execx.StartSpan(ctx, name)
+}
+
+// namespace returns ns, or the profile's NamespaceVar when ns is empty
+func (h *HelmRunner) namespace(ns string) string {
+	if ns == "" && h.profile != nil {
+		return h.profile.Get(NamespaceVar)
+	}
+	return ns
+}
+
+// values returns the profile's ValuesVar files followed by files,
+// so values given for an operation take precedence
+func (h *HelmRunner) values(files []string) []string {
+	if h.profile == nil {
+		return files
 	}
+	return append(h.profile.List(ValuesVar), files...)
 }
 
+// RetryPolicyFor returns the default retry policy for a helm subcommand.
+// Only subcommands that talk to chart repositories or registries are retried.
+func RetryPolicyFor(command string, args []string) execx.RetryPolicy {
+	if len(args) == 0 {
+		return execx.RetryPolicy{}
+	}
+	switch args[0] {
+	case "pull", "push":
+		return execx.DefaultRetryPolicy
+	case "repo":
+		if len(args) > 1 && (args[1] == "add" || args[1] == "update") {
+			return execx.DefaultRetryPolicy
+		}
+	case "dependency":
+		if len(args) > 1 && (args[1] == "update" || args[1] == "build") {
+			return execx.DefaultRetryPolicy
+		}
+	}
+	return execx.RetryPolicy{}
+}
+
+// lock acquires the named lock when locking is enabled and returns its release function
+func (h *HelmRunner) lock(ctx context.Context, name string) (unlock func(), err error) {
+	if !h.locking {
+		return func() {}, nil
+	}
+	l, err := lockx.Acquire(ctx, name, h.lockOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := l.Release(); err != nil {
+			logx.FromContext(ctx).Warn("⚠️ Failed to release lock", "name", name, "err", err)
+		}
+	}, nil
+}
+
+// releaseLock returns the lock name guarding a release
+func releaseLock(namespace, release string) string {
+	return lockx.Key("helm", "release", namespace, release)
+}
+
+// repositoriesLock is the lock name guarding the local repository configuration
+var repositoriesLock = lockx.Key("helm", "repositories")
+
 // InstallOptions contains options for helm install
 type InstallOptions struct {
 	ReleaseName     string
@@ -42,6 +246,11 @@ type InstallOptions struct {
 
 // Install installs a Helm chart
 func (h *HelmRunner) Install(opts InstallOptions) error {
+	return h.InstallContext(execx.Background(), opts)
+}
+
+// InstallContext is like Install but stops its commands when ctx is done
+func (h *HelmRunner) InstallContext(ctx context.Context, opts InstallOptions) error {
 	if opts.ReleaseName == "" {
 		return fmt.Errorf("release name is required")
 	}
@@ -49,14 +258,29 @@ func (h *HelmRunner) Install(opts InstallOptions) error {
 		return fmt.Errorf("chart is required")
 	}
 
-	slog.Info("📦 Installing Helm chart...",
-		"release", opts.ReleaseName,
+	opts.Namespace = h.namespace(opts.Namespace)
+	opts.Values = h.values(opts.Values)
+
+	ctx, span := h.start(ctx, "HelmRunner.Install")
+	defer span.Finish()
+
+	unlock, err := h.lock(ctx, releaseLock(opts.Namespace, opts.ReleaseName))
+	if err != nil {
+		return span.Fail(err)
+	}
+	defer unlock()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "install", "📦 Installing Helm chart...",
+		logx.KeyRelease, opts.ReleaseName,
 		"chart", opts.Chart,
 		"namespace", opts.Namespace,
 	)
 
-	start := time.Now()
-
 	args := []string{"install", opts.ReleaseName, opts.Chart}
 
 	if opts.Namespace != "" {
@@ -83,11 +307,11 @@ func (h *HelmRunner) Install(opts InstallOptions) error {
 		args = append(args, "--timeout", opts.Timeout)
 	}
 
-	if err := h.executor.Run(context.Background(), "helm", false, args...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, args...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm chart installed", "duration", time.Since(start))
+	step.Done("✅ Helm chart installed")
 	return nil
 }
 
@@ -105,6 +329,11 @@ type UpgradeOptions struct {
 
 // Upgrade upgrades a Helm release
 func (h *HelmRunner) Upgrade(opts UpgradeOptions) error {
+	return h.UpgradeContext(execx.Background(), opts)
+}
+
+// UpgradeContext is like Upgrade but stops its commands when ctx is done
+func (h *HelmRunner) UpgradeContext(ctx context.Context, opts UpgradeOptions) error {
 	if opts.ReleaseName == "" {
 		return fmt.Errorf("release name is required")
 	}
@@ -112,14 +341,29 @@ func (h *HelmRunner) Upgrade(opts UpgradeOptions) error {
 		return fmt.Errorf("chart is required")
 	}
 
-	slog.Info("🔄 Upgrading Helm release...",
-		"release", opts.ReleaseName,
+	opts.Namespace = h.namespace(opts.Namespace)
+	opts.Values = h.values(opts.Values)
+
+	ctx, span := h.start(ctx, "HelmRunner.Upgrade")
+	defer span.Finish()
+
+	unlock, err := h.lock(ctx, releaseLock(opts.Namespace, opts.ReleaseName))
+	if err != nil {
+		return span.Fail(err)
+	}
+	defer unlock()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "upgrade", "🔄 Upgrading Helm release...",
+		logx.KeyRelease, opts.ReleaseName,
 		"chart", opts.Chart,
 		"namespace", opts.Namespace,
 	)
 
-	start := time.Now()
-
 	args := []string{"upgrade", opts.ReleaseName, opts.Chart}
 
 	if opts.Namespace != "" {
@@ -146,27 +390,46 @@ func (h *HelmRunner) Upgrade(opts UpgradeOptions) error {
 		args = append(args, "--timeout", opts.Timeout)
 	}
 
-	if err := h.executor.Run(context.Background(), "helm", false, args...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, args...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm release upgraded", "duration", time.Since(start))
+	step.Done("✅ Helm release upgraded")
 	return nil
 }
 
 // Uninstall uninstalls a Helm release
 func (h *HelmRunner) Uninstall(releaseName, namespace string, args ...string) error {
+	return h.UninstallContext(execx.Background(), releaseName, namespace, args...)
+}
+
+// UninstallContext is like Uninstall but stops its commands when ctx is done
+func (h *HelmRunner) UninstallContext(ctx context.Context, releaseName, namespace string, args ...string) error {
 	if releaseName == "" {
 		return fmt.Errorf("release name is required")
 	}
 
-	slog.Info("🗑️  Uninstalling Helm release...",
-		"release", releaseName,
+	namespace = h.namespace(namespace)
+
+	ctx, span := h.start(ctx, "HelmRunner.Uninstall")
+	defer span.Finish()
+
+	unlock, err := h.lock(ctx, releaseLock(namespace, releaseName))
+	if err != nil {
+		return span.Fail(err)
+	}
+	defer unlock()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "uninstall", "🗑️  Uninstalling Helm release...",
+		logx.KeyRelease, releaseName,
 		"namespace", namespace,
 	)
 
-	start := time.Now()
-
 	cmdArgs := []string{"uninstall", releaseName}
 
 	if namespace != "" {
@@ -175,19 +438,30 @@ func (h *HelmRunner) Uninstall(releaseName, namespace string, args ...string) er
 
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm release uninstalled", "duration", time.Since(start))
+	step.Done("✅ Helm release uninstalled")
 	return nil
 }
 
 // List lists Helm releases
 func (h *HelmRunner) List(namespace string, args ...string) error {
-	slog.Info("📋 Listing Helm releases...", "namespace", namespace)
+	return h.ListContext(execx.Background(), namespace, args...)
+}
 
-	start := time.Now()
+// ListContext is like List but stops its commands when ctx is done
+func (h *HelmRunner) ListContext(ctx context.Context, namespace string, args ...string) error {
+	ctx, span := h.start(ctx, "HelmRunner.List")
+	defer span.Finish()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "list", "📋 Listing Helm releases...", "namespace", namespace)
 
 	cmdArgs := []string{"list"}
 
@@ -199,27 +473,40 @@ func (h *HelmRunner) List(namespace string, args ...string) error {
 
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm releases listed", "duration", time.Since(start))
+	step.Done("✅ Helm releases listed")
 	return nil
 }
 
 // Status shows the status of a Helm release
 func (h *HelmRunner) Status(releaseName, namespace string, args ...string) error {
+	return h.StatusContext(execx.Background(), releaseName, namespace, args...)
+}
+
+// StatusContext is like Status but stops its commands when ctx is done
+func (h *HelmRunner) StatusContext(ctx context.Context, releaseName, namespace string, args ...string) error {
 	if releaseName == "" {
 		return fmt.Errorf("release name is required")
 	}
 
-	slog.Info("📊 Getting Helm release status...",
-		"release", releaseName,
+	namespace = h.namespace(namespace)
+
+	ctx, span := h.start(ctx, "HelmRunner.Status")
+	defer span.Finish()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "status", "📊 Getting Helm release status...",
+		logx.KeyRelease, releaseName,
 		"namespace", namespace,
 	)
 
-	start := time.Now()
-
 	cmdArgs := []string{"status", releaseName}
 
 	if namespace != "" {
@@ -228,16 +515,21 @@ func (h *HelmRunner) Status(releaseName, namespace string, args ...string) error
 
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm release status retrieved", "duration", time.Since(start))
+	step.Done("✅ Helm release status retrieved")
 	return nil
 }
 
 // Template renders chart templates locally
 func (h *HelmRunner) Template(releaseName, chart string, args ...string) error {
+	return h.TemplateContext(execx.Background(), releaseName, chart, args...)
+}
+
+// TemplateContext is like Template but stops its commands when ctx is done
+func (h *HelmRunner) TemplateContext(ctx context.Context, releaseName, chart string, args ...string) error {
 	if releaseName == "" {
 		return fmt.Errorf("release name is required")
 	}
@@ -245,68 +537,160 @@ func (h *HelmRunner) Template(releaseName, chart string, args ...string) error {
 		return fmt.Errorf("chart is required")
 	}
 
-	slog.Info("📝 Rendering Helm templates...",
-		"release", releaseName,
+	ctx, span := h.start(ctx, "HelmRunner.Template")
+	defer span.Finish()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "template", "📝 Rendering Helm templates...",
+		logx.KeyRelease, releaseName,
 		"chart", chart,
 	)
 
-	start := time.Now()
-
 	cmdArgs := []string{"template", releaseName, chart}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm templates rendered", "duration", time.Since(start))
+	step.Done("✅ Helm templates rendered")
 	return nil
 }
 
 // Lint runs helm lint on a chart
 func (h *HelmRunner) Lint(chart string, args ...string) error {
+	return h.LintContext(execx.Background(), chart, args...)
+}
+
+// LintContext is like Lint but stops its commands when ctx is done
+func (h *HelmRunner) LintContext(ctx context.Context, chart string, args ...string) error {
 	if chart == "" {
 		return fmt.Errorf("chart path is required")
 	}
 
-	slog.Info("🔍 Linting Helm chart...", "chart", chart)
+	ctx, span := h.start(ctx, "HelmRunner.Lint")
+	defer span.Finish()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
 
-	start := time.Now()
+	step := logx.Begin(ctx, "helm", "lint", "🔍 Linting Helm chart...", "chart", chart)
 
 	cmdArgs := []string{"lint", chart}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm chart linted", "duration", time.Since(start))
+	step.Done("✅ Helm chart linted")
 	return nil
 }
 
-// Package packages a chart directory into a chart archive
+// Package packages a chart directory into a chart archive. With the default
+// executor, it is skipped when neither the chart nor the archive changed.
 func (h *HelmRunner) Package(chart string, args ...string) error {
+	return h.PackageContext(execx.Background(), chart, args...)
+}
+
+// PackageContext is like Package but stops its commands when ctx is done
+func (h *HelmRunner) PackageContext(ctx context.Context, chart string, args ...string) error {
 	if chart == "" {
 		return fmt.Errorf("chart path is required")
 	}
 
-	slog.Info("📦 Packaging Helm chart...", "chart", chart)
+	ctx, span := h.start(ctx, "HelmRunner.Package")
+	defer span.Finish()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
 
-	start := time.Now()
+	step := logx.Begin(ctx, "helm", "package", "📦 Packaging Helm chart...", "chart", chart)
 
 	cmdArgs := []string{"package", chart}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if archive, ok := packageArchive(chart, args); ok {
+		ctx = execx.WithOptions(ctx, execx.Cached(execx.CacheSpec{
+			Inputs:  []string{chart},
+			Outputs: []string{archive},
+		}))
+	}
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm chart packaged", "duration", time.Since(start))
+	step.Done("✅ Helm chart packaged")
 	return nil
 }
 
+// packageArchive returns the path of the archive `helm package` writes for
+// chart, from Chart.yaml and the --version and --destination flags. It reports
+// false when the output is unknown or packaging has other effects, such as
+// updating dependencies or signing.
+func packageArchive(chart string, args []string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(chart, "Chart.yaml"))
+	if err != nil {
+		return "", false
+	}
+	var name, version string
+	for line := range strings.Lines(string(data)) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value, _, _ = strings.Cut(value, " #")
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch key {
+		case "name":
+			name = value
+		case "version":
+			version = value
+		}
+	}
+
+	dest := "."
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		switch flag {
+		case "--version", "-d", "--destination":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return "", false
+				}
+				i++
+				value = args[i]
+			}
+			if flag == "--version" {
+				version = value
+			} else {
+				dest = value
+			}
+		case "-u", "--dependency-update", "--sign":
+			return "", false
+		}
+	}
+	if name == "" || version == "" {
+		return "", false
+	}
+	return filepath.Join(dest, name+"-"+version+".tgz"), true
+}
+
 // RepoAdd adds a chart repository
 func (h *HelmRunner) RepoAdd(name, url string, args ...string) error {
+	return h.RepoAddContext(execx.Background(), name, url, args...)
+}
+
+// RepoAddContext is like RepoAdd but stops its commands when ctx is done
+func (h *HelmRunner) RepoAddContext(ctx context.Context, name, url string, args ...string) error {
 	if name == "" {
 		return fmt.Errorf("repository name is required")
 	}
@@ -314,35 +698,64 @@ func (h *HelmRunner) RepoAdd(name, url string, args ...string) error {
 		return fmt.Errorf("repository URL is required")
 	}
 
-	slog.Info("➕ Adding Helm repository...", "name", name, "url", url)
+	ctx, span := h.start(ctx, "HelmRunner.RepoAdd")
+	defer span.Finish()
+
+	unlock, err := h.lock(ctx, repositoriesLock)
+	if err != nil {
+		return span.Fail(err)
+	}
+	defer unlock()
 
-	start := time.Now()
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "helm", "repo add", "➕ Adding Helm repository...", "name", name, "url", url)
 
 	cmdArgs := []string{"repo", "add", name, url}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm repository added", "duration", time.Since(start))
+	step.Done("✅ Helm repository added")
 	return nil
 }
 
 // RepoUpdate updates chart repositories
 func (h *HelmRunner) RepoUpdate(args ...string) error {
-	slog.Info("🔄 Updating Helm repositories...")
+	return h.RepoUpdateContext(execx.Background(), args...)
+}
+
+// RepoUpdateContext is like RepoUpdate but stops its commands when ctx is done
+func (h *HelmRunner) RepoUpdateContext(ctx context.Context, args ...string) error {
+	ctx, span := h.start(ctx, "HelmRunner.RepoUpdate")
+	defer span.Finish()
+
+	unlock, err := h.lock(ctx, repositoriesLock)
+	if err != nil {
+		return span.Fail(err)
+	}
+	defer unlock()
+
+	helm, err := h.tools.Command(ctx, "helm")
+	if err != nil {
+		return span.Fail(err)
+	}
 
-	start := time.Now()
+	step := logx.Begin(ctx, "helm", "repo update", "🔄 Updating Helm repositories...")
 
 	cmdArgs := []string{"repo", "update"}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := h.executor.Run(context.Background(), "helm", false, cmdArgs...); err != nil {
-		return err
+	if err := h.executor.Run(ctx, helm, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Helm repositories updated", "duration", time.Since(start))
+	step.Done("✅ Helm repositories updated")
 	return nil
 }
 
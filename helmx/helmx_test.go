@@ -0,0 +1,240 @@
+package helmx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/envx"
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/execx/execxtest"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
+)
+
+// newRunner returns a HelmRunner running its commands with fake and logging nowhere
+func newRunner(t *testing.T, fake *execxtest.Fake, opts ...Option) *HelmRunner {
+	t.Helper()
+	t.Setenv(toolx.BinDirEnv, t.TempDir())
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return NewHelmRunnerWithExecutor(fake, opts...)
+}
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name string
+		opts InstallOptions
+		want []string
+	}{
+		{
+			name: "minimal",
+			opts: InstallOptions{ReleaseName: "app", Chart: "./chart"},
+			want: []string{"install", "app", "./chart"},
+		},
+		{
+			name: "all flags",
+			opts: InstallOptions{
+				ReleaseName:     "app",
+				Chart:           "./chart",
+				Namespace:       "prod",
+				Values:          []string{"base.yaml", "prod.yaml"},
+				Set:             []string{"image.tag=v1"},
+				CreateNamespace: true,
+				Wait:            true,
+				Timeout:         "5m",
+			},
+			want: []string{
+				"install", "app", "./chart", "--namespace", "prod", "--create-namespace",
+				"--values", "base.yaml", "--values", "prod.yaml", "--set", "image.tag=v1",
+				"--wait", "--timeout", "5m",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := execxtest.New(t)
+			fake.Expect("helm", tt.want...)
+			if err := newRunner(t, fake).InstallContext(context.Background(), tt.opts); err != nil {
+				t.Errorf("InstallContext() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRequiredArguments(t *testing.T) {
+	h := newRunner(t, execxtest.New(t))
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"install without release", h.InstallContext(ctx, InstallOptions{Chart: "./chart"})},
+		{"install without chart", h.InstallContext(ctx, InstallOptions{ReleaseName: "app"})},
+		{"upgrade without release", h.UpgradeContext(ctx, UpgradeOptions{Chart: "./chart"})},
+		{"uninstall without release", h.UninstallContext(ctx, "", "prod")},
+		{"status without release", h.StatusContext(ctx, "", "prod")},
+		{"package without chart", h.PackageContext(ctx, "")},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || !strings.Contains(tt.err.Error(), "required") {
+			t.Errorf("%s: error = %v, want a required argument error", tt.name, tt.err)
+		}
+	}
+}
+
+func TestUpgradeWithProfile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env.staging"), []byte("HELM_NAMESPACE=staging\nHELM_VALUES=base.yaml, staging.yaml\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := envx.Load(dir, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("helm", "upgrade", "app", "./chart", "--namespace", "staging", "--install",
+		"--values", "base.yaml", "--values", "staging.yaml", "--values", "mine.yaml")
+	fake.Expect("helm", "upgrade", "app", "./chart", "--namespace", "other",
+		"--values", "base.yaml", "--values", "staging.yaml")
+
+	h := newRunner(t, fake, WithProfile(profile))
+	ctx := context.Background()
+	if err := h.UpgradeContext(ctx, UpgradeOptions{ReleaseName: "app", Chart: "./chart", Install: true, Values: []string{"mine.yaml"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.UpgradeContext(ctx, UpgradeOptions{ReleaseName: "app", Chart: "./chart", Namespace: "other"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListAndUninstall(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("helm", "list", "--all-namespaces")
+	fake.Expect("helm", "list", "--namespace", "prod", "-o", "json")
+	fake.Expect("helm", "uninstall", "app", "--namespace", "prod", "--wait")
+
+	h := newRunner(t, fake)
+	ctx := context.Background()
+	if err := h.ListContext(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ListContext(ctx, "prod", "-o", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.UninstallContext(ctx, "app", "prod", "--wait"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailedCommand(t *testing.T) {
+	fake := execxtest.New(t)
+	fake.Expect("helm", "status", "app", "--namespace", "prod").
+		Stderr("Error: release: not found\n").
+		ExitCode(1)
+
+	err := newRunner(t, fake).StatusContext(context.Background(), "app", "prod")
+	var exitErr *execxtest.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("StatusContext() error = %v, want exit code 1", err)
+	}
+}
+
+func TestWithBinary(t *testing.T) {
+	fake := execxtest.New(t)
+	fake.Expect("helm", "repo", "update")
+
+	h := newRunner(t, fake, WithBinary("helm", "/opt/helm/bin/helm"))
+	if err := h.RepoUpdateContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Invocations()[0].Command; got != "/opt/helm/bin/helm" {
+		t.Errorf("command = %q, want the configured binary", got)
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	tests := []struct {
+		args  []string
+		retry bool
+	}{
+		{nil, false},
+		{[]string{"pull", "oci://example.com/chart"}, true},
+		{[]string{"push", "chart.tgz", "oci://example.com"}, true},
+		{[]string{"repo", "add", "stable", "https://example.com"}, true},
+		{[]string{"repo", "update"}, true},
+		{[]string{"repo", "list"}, false},
+		{[]string{"dependency", "build"}, true},
+		{[]string{"dependency", "list"}, false},
+		{[]string{"upgrade", "app", "."}, false},
+	}
+	for _, tt := range tests {
+		policy := RetryPolicyFor("helm", tt.args)
+		if got := policy.Attempts > 1; got != tt.retry {
+			t.Errorf("RetryPolicyFor(%q) retries = %v, want %v", tt.args, got, tt.retry)
+		}
+	}
+}
+
+func TestPackageArchive(t *testing.T) {
+	chart := t.TempDir()
+	chartYAML := "apiVersion: v2\nname: \"app\" # the app\nversion: 1.2.3\n"
+	if err := os.WriteFile(filepath.Join(chart, "Chart.yaml"), []byte(chartYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		want string
+		ok   bool
+	}{
+		{nil, "app-1.2.3.tgz", true},
+		{[]string{"--version", "2.0.0"}, "app-2.0.0.tgz", true},
+		{[]string{"--version=2.0.0", "-d", "dist"}, filepath.Join("dist", "app-2.0.0.tgz"), true},
+		{[]string{"--destination=dist"}, filepath.Join("dist", "app-1.2.3.tgz"), true},
+		{[]string{"--destination"}, "", false},
+		{[]string{"-u"}, "", false},
+		{[]string{"--sign"}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := packageArchive(chart, tt.args)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("packageArchive(%q) = %q, %v, want %q, %v", tt.args, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := packageArchive(t.TempDir(), nil); ok {
+		t.Error("packageArchive() of a directory without Chart.yaml reports an archive")
+	}
+}
+
+func TestNewHelmRunnerChecksTools(t *testing.T) {
+	t.Setenv(toolx.BinDirEnv, t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv(execx.DryRunEnv, "")
+
+	h := NewHelmRunner(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	err := h.StatusContext(context.Background(), "app", "prod")
+	var notFound *toolx.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("StatusContext() error = %v, want the missing helm reported", err)
+	}
+}
+
+func TestLogsRelease(t *testing.T) {
+	fake := execxtest.New(t)
+	fake.Expect("helm", "uninstall", "app", "--namespace", "prod")
+
+	var logs bytes.Buffer
+	h := newRunner(t, fake, WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+	if err := h.UninstallContext(context.Background(), "app", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"` + logx.KeyRelease + `":"app"`; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs lack %s:\n%s", want, logs.String())
+	}
+}
@@ -1,35 +1,90 @@
 package komagex
 
 import (
+	"context"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
 	"github.com/vinaycharlie01/go-mage-shared/kox"
 )
 
 // Package-level convenience functions for mage targets
-var defaultRunner = kox.NewKoRunner()
+var (
+	defaultExecutor execx.Executor = execx.NewDefaultExecutor()
+	defaultOptions  []kox.Option
+	defaultRunner   = newDefaultRunner(defaultExecutor)
+)
+
+// newDefaultRunner creates the runner of the package-level functions.
+// Like kox.NewKoRunner, it verifies tools before first use unless commands are only printed.
+func newDefaultRunner(executor execx.Executor, opts ...kox.Option) *kox.KoRunner {
+	if !execx.IsDryRun(executor) {
+		opts = append([]kox.Option{kox.WithToolCheck(nil)}, opts...)
+	}
+	return kox.NewKoRunnerWithExecutor(executor, opts...)
+}
+
+// SetDefaultExecutor replaces the executor used by the package-level functions,
+// e.g. with execx.NewDryRun() to print commands for the rest of a mage run
+func SetDefaultExecutor(executor execx.Executor) {
+	defaultExecutor = executor
+	defaultRunner = newDefaultRunner(executor, defaultOptions...)
+}
+
+// SetDefaultOptions configures the runner used by the package-level functions,
+// e.g. with kox.WithProfile to apply an environment profile
+func SetDefaultOptions(opts ...kox.Option) {
+	defaultOptions = opts
+	defaultRunner = newDefaultRunner(defaultExecutor, opts...)
+}
 
 // Build builds a container image using ko
 func Build(opts kox.BuildOptions) error {
 	return defaultRunner.Build(opts)
 }
 
+// BuildContext is like Build but stops its commands when ctx is done
+func BuildContext(ctx context.Context, opts kox.BuildOptions) error {
+	return defaultRunner.BuildContext(ctx, opts)
+}
+
 // Apply builds images and applies Kubernetes manifests
 func Apply(opts kox.ApplyOptions) error {
 	return defaultRunner.Apply(opts)
 }
 
+// ApplyContext is like Apply but stops its commands when ctx is done
+func ApplyContext(ctx context.Context, opts kox.ApplyOptions) error {
+	return defaultRunner.ApplyContext(ctx, opts)
+}
+
 // Delete deletes Kubernetes resources
 func Delete(opts kox.DeleteOptions) error {
 	return defaultRunner.Delete(opts)
 }
 
+// DeleteContext is like Delete but stops its commands when ctx is done
+func DeleteContext(ctx context.Context, opts kox.DeleteOptions) error {
+	return defaultRunner.DeleteContext(ctx, opts)
+}
+
 // Resolve resolves import paths to image references
 func Resolve(importPaths []string, args ...string) error {
 	return defaultRunner.Resolve(importPaths, args...)
 }
 
+// ResolveContext is like Resolve but stops its commands when ctx is done
+func ResolveContext(ctx context.Context, importPaths []string, args ...string) error {
+	return defaultRunner.ResolveContext(ctx, importPaths, args...)
+}
+
 // Publish publishes images for import paths
 func Publish(importPath string, args ...string) error {
 	return defaultRunner.Publish(importPath, args...)
 }
 
+// PublishContext is like Publish but stops its commands when ctx is done
+func PublishContext(ctx context.Context, importPath string, args ...string) error {
+	return defaultRunner.PublishContext(ctx, importPath, args...)
+}
+
 // Made with Bob
@@ -1,31 +1,167 @@
 package kox
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/vinaycharlie01/go-mage-shared/envx"
 	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/golang"
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
 )
 
 // KoRunner handles ko command execution with dependency injection
 type KoRunner struct {
 	executor execx.Executor
+	tools    *toolx.Set
+	logger   *slog.Logger
+	passEnv  []string
+	profile  *envx.Profile
 }
 
-// NewKoRunner creates a new KoRunner with the default executor
-func NewKoRunner() *KoRunner {
-	return &KoRunner{
-		executor: execx.NewExec(),
+// Option configures a KoRunner
+type Option func(*KoRunner)
+
+// WithRetry retries subcommands that pull base images or push to a registry
+// with the policies from RetryPolicyFor
+func WithRetry() Option {
+	return func(k *KoRunner) {
+		k.executor = execx.NewRetryWithPolicies(k.executor, RetryPolicyFor)
+	}
+}
+
+// WithRedactor masks the secrets known to r in echoed commands,
+// streamed and captured output and errors of every command the runner executes,
+// instead of execx.DefaultRedactor.
+func WithRedactor(r *execx.Redactor) Option {
+	return func(k *KoRunner) {
+		k.executor = execx.NewChain(k.executor, execx.RedactionInterceptor(r))
+	}
+}
+
+// Tools lists the binaries the runner executes and the versions it supports
+var Tools = []toolx.Requirement{
+	{Tool: "ko", Constraint: ">=0.15"},
+}
+
+// PassEnv lists the variables the runner's tools legitimately read from our
+// environment: the registry to push to, its credentials, and what the Go
+// toolchain ko builds with needs to fetch modules. Hermetic commands inherit
+// them, see execx.Hermetic.
+var PassEnv = []string{
+	"KO_DOCKER_REPO", "DOCKER_CONFIG",
+	"GOPATH", "GOCACHE", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GONOPROXY", "GONOSUMDB", "GOSUMDB", "GOINSECURE", "GOAUTH",
+	"GIT_ASKPASS", "GIT_SSH_COMMAND", "SSH_AUTH_SOCK", "NETRC",
+}
+
+// WithPassEnv declares more variables the runner's commands inherit when they
+// run hermetic, e.g. "KUBECONFIG"
+func WithPassEnv(names ...string) Option {
+	return func(k *KoRunner) {
+		k.passEnv = append(k.passEnv, names...)
+	}
+}
+
+// WithProfile runs the runner's commands with the variables of p, so ko
+// takes KO_DOCKER_REPO, KO_DEFAULTPLATFORMS and the like from it
+func WithProfile(p *envx.Profile) Option {
+	return func(k *KoRunner) {
+		k.profile = p
+	}
+}
+
+// WithBinary runs tool from path instead of looking it up in PATH
+func WithBinary(tool, path string) Option {
+	return func(k *KoRunner) {
+		k.tools.UsePath(tool, path)
+	}
+}
+
+// WithToolCheck verifies that each tool is installed and satisfies Tools
+// before it is first used, with toolx.DefaultRegistry when registry is nil
+func WithToolCheck(registry *toolx.Registry) Option {
+	return func(k *KoRunner) {
+		if registry == nil {
+			registry = toolx.DefaultRegistry()
+		}
+		k.tools.VerifyWith(registry)
 	}
 }
 
+// WithoutToolCheck runs tools without verifying them first
+func WithoutToolCheck() Option {
+	return func(k *KoRunner) {
+		k.tools.VerifyWith(nil)
+	}
+}
+
+// WithLogger reports the runner's progress, and the output of commands run
+// with streamToLog, to l instead of logx.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(k *KoRunner) {
+		k.logger = l
+	}
+}
+
+// WithLogMode reports the runner's progress to stderr in mode, e.g. logx.Quiet
+func WithLogMode(mode logx.Mode) Option {
+	return WithLogger(logx.New(os.Stderr, mode))
+}
+
+// NewKoRunner creates a new KoRunner with the default executor.
+// Commands are only printed when execx.DryRunEnv is set; otherwise each tool
+// is verified with toolx.DefaultRegistry before it is first used.
+func NewKoRunner(opts ...Option) *KoRunner {
+	executor := execx.NewDefaultExecutor()
+	if !execx.IsDryRun(executor) {
+		opts = append([]Option{WithToolCheck(nil)}, opts...)
+	}
+	return NewKoRunnerWithExecutor(executor, opts...)
+}
+
 // NewKoRunnerWithExecutor creates a new KoRunner with a custom executor
-func NewKoRunnerWithExecutor(executor execx.Executor) *KoRunner {
-	return &KoRunner{
+func NewKoRunnerWithExecutor(executor execx.Executor, opts ...Option) *KoRunner {
+	k := &KoRunner{
 		executor: executor,
+		tools:    toolx.NewSet(Tools...),
+		passEnv:  slices.Clone(PassEnv),
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// start begins the span of a runner method, makes the runner's logger the
+// one its commands report to and gives them the variables they need.
+// Secrets are masked with execx.DefaultRedactor unless ctx sets a Redactor.
+func (k *KoRunner) start(ctx context.Context, name string) (context.Context, *execx.Span) {
+	if k.logger != nil {
+		ctx = logx.NewContext(ctx, k.logger)
 	}
+	ctx = execx.WithOptions(ctx, execx.PassEnv(k.passEnv...), execx.DefaultRedaction())
+	ctx = k.profile.Context(ctx)
+	return execx.StartSpan(ctx, name)
+}
+
+// RetryPolicyFor returns the default retry policy for a ko subcommand.
+// Subcommands that pull base images or push to a registry are retried.
+func RetryPolicyFor(command string, args []string) execx.RetryPolicy {
+	if len(args) == 0 {
+		return execx.RetryPolicy{}
+	}
+	switch args[0] {
+	case "build", "publish", "resolve":
+		return execx.DefaultRetryPolicy
+	}
+	return execx.RetryPolicy{}
 }
 
 // BuildOptions contains options for ko build
@@ -38,22 +174,36 @@ type BuildOptions struct {
 	Local               bool     // Build locally without pushing
 	Push                bool     // Push to registry
 	PreserveImportPaths bool     // Preserve import paths in image names
+	Tarball             string   // Write the image to this tarball; it is only pushed if Push is set
 }
 
-// Build builds a container image using ko
+// Build builds a container image using ko. With the default executor, a build
+// writing only a Tarball is skipped when neither the sources nor the tarball
+// changed, provided its base images are pinned by digest.
 func (k *KoRunner) Build(opts BuildOptions) error {
+	return k.BuildContext(execx.Background(), opts)
+}
+
+// BuildContext is like Build but stops its commands when ctx is done
+func (k *KoRunner) BuildContext(ctx context.Context, opts BuildOptions) error {
 	if opts.ImportPath == "" {
 		return fmt.Errorf("import path is required")
 	}
 
-	slog.Info("🐳 Building container image with ko...",
+	ctx, span := k.start(ctx, "KoRunner.Build")
+	defer span.Finish()
+
+	ko, err := k.tools.Command(ctx, "ko")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "ko", "build", "🐳 Building container image with ko...",
 		"importPath", opts.ImportPath,
 		"local", opts.Local,
 		"push", opts.Push,
 	)
 
-	start := time.Now()
-
 	args := []string{"build", opts.ImportPath}
 
 	for _, tag := range opts.Tags {
@@ -84,14 +234,102 @@ func (k *KoRunner) Build(opts BuildOptions) error {
 		args = append(args, "--preserve-import-paths")
 	}
 
-	if err := k.executor.Run(context.Background(), "ko", false, args...); err != nil {
-		return err
+	if opts.Tarball != "" {
+		args = append(args, "--tarball", opts.Tarball)
+		if !opts.Push {
+			args = append(args, "--push=false")
+		}
 	}
 
-	slog.Info("✅ Container image built", "duration", time.Since(start))
+	// Only a build without side effects beyond the tarball can be skipped
+	if opts.Tarball != "" && !opts.Push && !opts.Local {
+		if cache, ok := k.tarballCache(ctx, opts.Tarball); ok {
+			ctx = execx.WithOptions(ctx, execx.Cached(cache))
+		}
+	}
+	if err := k.executor.Run(ctx, ko, false, args...); err != nil {
+		return span.Fail(step.Fail(err))
+	}
+
+	step.Done("✅ Container image built")
 	return nil
 }
 
+// tarballCache lets the default executor skip a build of tarball whose
+// sources, configuration, Go build settings and tarball are unchanged.
+// A base image that is not pinned by digest may change at any time, so the
+// build is only cached when every base image is.
+func (k *KoRunner) tarballCache(ctx context.Context, tarball string) (execx.CacheSpec, bool) {
+	if execx.IsDryRun(k.executor) {
+		return execx.CacheSpec{}, false
+	}
+	opts := execx.OptionsFrom(ctx)
+	if !pinnedBaseImages(execx.MergeEnv(execx.MergeEnv(os.Environ(), opts.BaseEnv), opts.Env)) {
+		logx.FromContext(ctx).DebugContext(ctx, "Image not cached, its base image is not pinned by digest")
+		return execx.CacheSpec{}, false
+	}
+	settings, err := golang.BuildSettings(ctx, k.executor, "go")
+	if err != nil {
+		logx.FromContext(ctx).DebugContext(ctx, "Image not cached, cannot read the Go build settings", "err", err)
+		return execx.CacheSpec{}, false
+	}
+	return execx.CacheSpec{
+		Inputs:  append(golang.SourceInputs(""), ".ko.yaml"),
+		Env:     []string{"KO_DOCKER_REPO", "KO_DEFAULTBASEIMAGE", "KO_DEFAULTPLATFORMS", "KO_CONFIG_PATH"},
+		Keys:    settings,
+		Outputs: []string{tarball},
+	}, true
+}
+
+// pinnedBaseImages reports whether the default base image and every override
+// in the ko configuration of env are given by digest, e.g. image@sha256:...
+// ko's own default base image is a tag.
+func pinnedBaseImages(env []string) bool {
+	images := []string{lookup(env, "KO_DEFAULTBASEIMAGE")}
+	config := cmp.Or(lookup(env, "KO_CONFIG_PATH"), ".")
+	if info, err := os.Stat(config); err == nil && info.IsDir() {
+		config = filepath.Join(config, ".ko.yaml")
+	}
+	if data, err := os.ReadFile(config); err == nil {
+		overrides := false
+		for line := range strings.Lines(string(data)) {
+			key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch {
+			case key == "defaultBaseImage":
+				if images[0] == "" {
+					images[0] = value
+				}
+				overrides = false
+			case key == "baseImageOverrides":
+				overrides = true
+			case overrides && line != "" && (line[0] == ' ' || line[0] == '\t'):
+				if value != "" {
+					images = append(images, value)
+				}
+			case strings.TrimSpace(line) != "":
+				overrides = false
+			}
+		}
+	}
+	for _, image := range images {
+		if !strings.Contains(image, "@sha256:") {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of the variable name in env, the last one wins
+func lookup(env []string, name string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if key, value, ok := strings.Cut(env[i], "="); ok && key == name {
+			return value
+		}
+	}
+	return ""
+}
+
 // ApplyOptions contains options for ko apply
 type ApplyOptions struct {
 	Filenames           []string // Kubernetes manifest files
@@ -106,17 +344,28 @@ type ApplyOptions struct {
 
 // Apply builds images and applies Kubernetes manifests
 func (k *KoRunner) Apply(opts ApplyOptions) error {
+	return k.ApplyContext(execx.Background(), opts)
+}
+
+// ApplyContext is like Apply but stops its commands when ctx is done
+func (k *KoRunner) ApplyContext(ctx context.Context, opts ApplyOptions) error {
 	if len(opts.Filenames) == 0 {
 		return fmt.Errorf("at least one filename is required")
 	}
 
-	slog.Info("🚀 Building and applying with ko...",
+	ctx, span := k.start(ctx, "KoRunner.Apply")
+	defer span.Finish()
+
+	ko, err := k.tools.Command(ctx, "ko")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "ko", "apply", "🚀 Building and applying with ko...",
 		"files", opts.Filenames,
 		"local", opts.Local,
 	)
 
-	start := time.Now()
-
 	args := []string{"apply"}
 
 	for _, filename := range opts.Filenames {
@@ -151,11 +400,11 @@ func (k *KoRunner) Apply(opts ApplyOptions) error {
 		args = append(args, "--preserve-import-paths")
 	}
 
-	if err := k.executor.Run(context.Background(), "ko", false, args...); err != nil {
-		return err
+	if err := k.executor.Run(ctx, ko, false, args...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Images built and manifests applied", "duration", time.Since(start))
+	step.Done("✅ Images built and manifests applied")
 	return nil
 }
 
@@ -168,13 +417,24 @@ type DeleteOptions struct {
 
 // Delete deletes Kubernetes resources
 func (k *KoRunner) Delete(opts DeleteOptions) error {
+	return k.DeleteContext(execx.Background(), opts)
+}
+
+// DeleteContext is like Delete but stops its commands when ctx is done
+func (k *KoRunner) DeleteContext(ctx context.Context, opts DeleteOptions) error {
 	if len(opts.Filenames) == 0 {
 		return fmt.Errorf("at least one filename is required")
 	}
 
-	slog.Info("🗑️  Deleting resources with ko...", "files", opts.Filenames)
+	ctx, span := k.start(ctx, "KoRunner.Delete")
+	defer span.Finish()
 
-	start := time.Now()
+	ko, err := k.tools.Command(ctx, "ko")
+	if err != nil {
+		return span.Fail(err)
+	}
+
+	step := logx.Begin(ctx, "ko", "delete", "🗑️  Deleting resources with ko...", "files", opts.Filenames)
 
 	args := []string{"delete"}
 
@@ -190,54 +450,76 @@ func (k *KoRunner) Delete(opts DeleteOptions) error {
 		args = append(args, "--selector", opts.Selector)
 	}
 
-	if err := k.executor.Run(context.Background(), "ko", false, args...); err != nil {
-		return err
+	if err := k.executor.Run(ctx, ko, false, args...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Resources deleted", "duration", time.Since(start))
+	step.Done("✅ Resources deleted")
 	return nil
 }
 
 // Resolve resolves import paths to image references
 func (k *KoRunner) Resolve(importPaths []string, args ...string) error {
+	return k.ResolveContext(execx.Background(), importPaths, args...)
+}
+
+// ResolveContext is like Resolve but stops its commands when ctx is done
+func (k *KoRunner) ResolveContext(ctx context.Context, importPaths []string, args ...string) error {
 	if len(importPaths) == 0 {
 		return fmt.Errorf("at least one import path is required")
 	}
 
-	slog.Info("🔍 Resolving import paths...", "paths", importPaths)
+	ctx, span := k.start(ctx, "KoRunner.Resolve")
+	defer span.Finish()
+
+	ko, err := k.tools.Command(ctx, "ko")
+	if err != nil {
+		return span.Fail(err)
+	}
 
-	start := time.Now()
+	step := logx.Begin(ctx, "ko", "resolve", "🔍 Resolving import paths...", "paths", importPaths)
 
 	cmdArgs := []string{"resolve"}
 	cmdArgs = append(cmdArgs, args...)
 	cmdArgs = append(cmdArgs, importPaths...)
 
-	if err := k.executor.Run(context.Background(), "ko", false, cmdArgs...); err != nil {
-		return err
+	if err := k.executor.Run(ctx, ko, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Import paths resolved", "duration", time.Since(start))
+	step.Done("✅ Import paths resolved")
 	return nil
 }
 
 // Publish publishes images for import paths
 func (k *KoRunner) Publish(importPath string, args ...string) error {
+	return k.PublishContext(execx.Background(), importPath, args...)
+}
+
+// PublishContext is like Publish but stops its commands when ctx is done
+func (k *KoRunner) PublishContext(ctx context.Context, importPath string, args ...string) error {
 	if importPath == "" {
 		return fmt.Errorf("import path is required")
 	}
 
-	slog.Info("📤 Publishing image...", "importPath", importPath)
+	ctx, span := k.start(ctx, "KoRunner.Publish")
+	defer span.Finish()
+
+	ko, err := k.tools.Command(ctx, "ko")
+	if err != nil {
+		return span.Fail(err)
+	}
 
-	start := time.Now()
+	step := logx.Begin(ctx, "ko", "publish", "📤 Publishing image...", "importPath", importPath)
 
 	cmdArgs := []string{"publish", importPath}
 	cmdArgs = append(cmdArgs, args...)
 
-	if err := k.executor.Run(context.Background(), "ko", false, cmdArgs...); err != nil {
-		return err
+	if err := k.executor.Run(ctx, ko, false, cmdArgs...); err != nil {
+		return span.Fail(step.Fail(err))
 	}
 
-	slog.Info("✅ Image published", "duration", time.Since(start))
+	step.Done("✅ Image published")
 	return nil
 }
 
@@ -0,0 +1,181 @@
+package kox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx/execxtest"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
+)
+
+// newRunner returns a KoRunner running its commands with fake and logging nowhere
+func newRunner(t *testing.T, fake *execxtest.Fake, opts ...Option) *KoRunner {
+	t.Helper()
+	t.Setenv(toolx.BinDirEnv, t.TempDir())
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return NewKoRunnerWithExecutor(fake, opts...)
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		opts BuildOptions
+		want []string
+	}{
+		{
+			name: "minimal",
+			opts: BuildOptions{ImportPath: "./cmd/app"},
+			want: []string{"build", "./cmd/app"},
+		},
+		{
+			name: "push",
+			opts: BuildOptions{
+				ImportPath: "./cmd/app",
+				Tags:       []string{"latest", "v1"},
+				Platform:   []string{"linux/amd64", "linux/arm64"},
+				BaseImage:  "cgr.dev/chainguard/static",
+				Bare:       true,
+				Push:       true,
+			},
+			want: []string{
+				"build", "./cmd/app", "--tags", "latest", "--tags", "v1",
+				"--platform", "linux/amd64", "--platform", "linux/arm64",
+				"--base-import-paths", "cgr.dev/chainguard/static", "--bare", "--push",
+			},
+		},
+		{
+			name: "tarball only",
+			opts: BuildOptions{ImportPath: "./cmd/app", Local: true, PreserveImportPaths: true, Tarball: "image.tar"},
+			want: []string{"build", "./cmd/app", "--local", "--preserve-import-paths", "--tarball", "image.tar", "--push=false"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := execxtest.New(t)
+			fake.Expect("ko", tt.want...)
+			if err := newRunner(t, fake).BuildContext(context.Background(), tt.opts); err != nil {
+				t.Errorf("BuildContext() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyAndDelete(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("ko", "apply", "-f", "config/", "--recursive", "--selector", "app=web", "--platform", "linux/amd64", "--local")
+	fake.Expect("ko", "delete", "-f", "a.yaml", "-f", "b.yaml")
+
+	k := newRunner(t, fake)
+	ctx := context.Background()
+	if err := k.ApplyContext(ctx, ApplyOptions{
+		Filenames: []string{"config/"},
+		Recursive: true,
+		Selector:  "app=web",
+		Platform:  []string{"linux/amd64"},
+		Local:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.DeleteContext(ctx, DeleteOptions{Filenames: []string{"a.yaml", "b.yaml"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveAndPublish(t *testing.T) {
+	fake := execxtest.New(t).InOrder()
+	fake.Expect("ko", "resolve", "--bare", "./cmd/a", "./cmd/b")
+	fake.Expect("ko", "publish", "./cmd/a", "--bare")
+
+	k := newRunner(t, fake, WithBinary("ko", "/opt/ko/ko"))
+	ctx := context.Background()
+	if err := k.ResolveContext(ctx, []string{"./cmd/a", "./cmd/b"}, "--bare"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.PublishContext(ctx, "./cmd/a", "--bare"); err != nil {
+		t.Fatal(err)
+	}
+	for _, inv := range fake.Invocations() {
+		if inv.Command != "/opt/ko/ko" {
+			t.Errorf("command = %q, want the configured binary", inv.Command)
+		}
+	}
+}
+
+func TestRequiredArguments(t *testing.T) {
+	k := newRunner(t, execxtest.New(t))
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"build", k.BuildContext(ctx, BuildOptions{})},
+		{"apply", k.ApplyContext(ctx, ApplyOptions{})},
+		{"delete", k.DeleteContext(ctx, DeleteOptions{})},
+		{"resolve", k.ResolveContext(ctx, nil)},
+		{"publish", k.PublishContext(ctx, "")},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || !strings.Contains(tt.err.Error(), "required") {
+			t.Errorf("%s: error = %v, want a required argument error", tt.name, tt.err)
+		}
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	for _, sub := range []string{"build", "publish", "resolve"} {
+		if RetryPolicyFor("ko", []string{sub}).Attempts <= 1 {
+			t.Errorf("ko %s is not retried", sub)
+		}
+	}
+	for _, args := range [][]string{nil, {"apply"}, {"delete"}, {"version"}} {
+		if RetryPolicyFor("ko", args).Attempts > 1 {
+			t.Errorf("ko %q is retried", args)
+		}
+	}
+}
+
+func TestPinnedBaseImages(t *testing.T) {
+	const digest = "@sha256:0123456789abcdef"
+	tests := []struct {
+		name   string
+		env    []string
+		config string
+		want   bool
+	}{
+		{"ko default", nil, "", false},
+		{"env tag", []string{"KO_DEFAULTBASEIMAGE=cgr.dev/chainguard/static:latest"}, "", false},
+		{"env digest", []string{"KO_DEFAULTBASEIMAGE=cgr.dev/chainguard/static" + digest}, "", true},
+		{"config digest", nil, "defaultBaseImage: cgr.dev/chainguard/static" + digest + "\n", true},
+		{
+			"override tag",
+			nil,
+			"defaultBaseImage: \"cgr.dev/chainguard/static" + digest + "\"\nbaseImageOverrides:\n  example.com/app/cmd/debug: busybox:latest\nbuilds: []\n",
+			false,
+		},
+		{
+			"overrides pinned",
+			nil,
+			"defaultBaseImage: cgr.dev/chainguard/static" + digest + "\nbaseImageOverrides:\n  example.com/app/cmd/debug: localhost:5000/busybox" + digest + "\n",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			env := append([]string{"KO_CONFIG_PATH=" + dir}, tt.env...)
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".ko.yaml"), []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := pinnedBaseImages(env); got != tt.want {
+				t.Errorf("pinnedBaseImages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
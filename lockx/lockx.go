@@ -0,0 +1,325 @@
+// Package lockx provides cross-process locks keyed by arbitrary names, so that
+// conflicting operations such as two upgrades of the same Helm release, or two
+// builds into one output directory, run one at a time on a machine.
+//
+// A lock is a file holding the identity of its owner. The owner refreshes the
+// file while it holds the lock, so a lock left behind by a crashed or killed
+// process is detected as stale and taken over.
+package lockx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinaycharlie01/go-mage-shared/logx"
+)
+
+// DirEnv is the environment variable overriding the default lock directory
+const DirEnv = "LOCKX_DIR"
+
+// Defaults for Options
+const (
+	DefaultStaleAfter   = time.Minute
+	DefaultPollInterval = 250 * time.Millisecond
+)
+
+// Options configures how a lock is acquired
+type Options struct {
+	Dir          string        // Directory holding lock files; DefaultDir when empty
+	Timeout      time.Duration // Maximum time to wait for the lock; zero waits until ctx is done
+	StaleAfter   time.Duration // A lock not refreshed for this long is stale; DefaultStaleAfter when zero
+	PollInterval time.Duration // Delay between attempts; DefaultPollInterval when zero
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// Dir sets the directory holding lock files
+func Dir(dir string) Option {
+	return func(o *Options) {
+		o.Dir = dir
+	}
+}
+
+// Timeout bounds how long Acquire waits for the lock
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// StaleAfter sets how long a lock may go without being refreshed before it is taken over
+func StaleAfter(d time.Duration) Option {
+	return func(o *Options) {
+		o.StaleAfter = d
+	}
+}
+
+// DefaultDir returns the directory lock files are created in by default.
+// It is shared by all users of the machine unless DirEnv is set.
+func DefaultDir() string {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "go-mage-shared-locks")
+}
+
+// Key joins parts into a lock name, e.g. Key("helm", namespace, release)
+func Key(parts ...string) string {
+	return strings.Join(parts, "/")
+}
+
+// Holder identifies the process owning a lock
+type Holder struct {
+	Name     string    `json:"name"`
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+	Token    string    `json:"token"`
+}
+
+// String describes the holder for log and error messages
+func (h Holder) String() string {
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Host, h.Acquired.Format(time.RFC3339))
+}
+
+// TimeoutError is returned when a lock could not be acquired in time
+type TimeoutError struct {
+	Name    string
+	Holder  Holder
+	Timeout time.Duration
+}
+
+// Error implements the error interface
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for lock %q held by %s", e.Timeout, e.Name, e.Holder)
+}
+
+// Lock is a held cross-process lock
+type Lock struct {
+	name    string
+	path    string
+	content []byte
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Acquire waits until the lock called name is free and takes it.
+// Locks held by processes that are gone or stopped refreshing are taken over.
+func Acquire(ctx context.Context, name string, opts ...Option) (*Lock, error) {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Dir == "" {
+		o.Dir = DefaultDir()
+	}
+	if o.StaleAfter <= 0 {
+		o.StaleAfter = DefaultStaleAfter
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	if err := os.MkdirAll(o.Dir, 0o777); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	if o.Dir == DefaultDir() {
+		// Let other users of the machine take locks too; fails harmlessly if not ours
+		_ = os.Chmod(o.Dir, 0o777)
+	}
+
+	host, _ := os.Hostname()
+	content, err := json.Marshal(Holder{
+		Name:     name,
+		PID:      os.Getpid(),
+		Host:     host,
+		Acquired: time.Now(),
+		Token:    randomToken(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lock holder: %w", err)
+	}
+	l := &Lock{name: name, path: filepath.Join(o.Dir, fileName(name)), content: content}
+
+	var deadline <-chan time.Time
+	if o.Timeout > 0 {
+		timer := time.NewTimer(o.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	start := time.Now()
+	waiting := false
+	for {
+		err := l.create()
+		if err == nil {
+			if waiting {
+				logx.FromContext(ctx).InfoContext(ctx, "🔒 Lock acquired", "name", name, "waited", time.Since(start))
+			}
+			l.heartbeat(o.StaleAfter / 4)
+			return l, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %q: %w", name, err)
+		}
+
+		holder, observed, reason := l.inspect(o.StaleAfter)
+		if reason != "" {
+			if l.breakStale(observed) {
+				logx.FromContext(ctx).WarnContext(ctx, "🔓 Took over stale lock", "name", name, "holder", holder, "reason", reason)
+			}
+			continue
+		}
+		if !waiting {
+			waiting = true
+			logx.FromContext(ctx).InfoContext(ctx, "⏳ Waiting for lock", "name", name, "holder", holder)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("canceled waiting for lock %q held by %s: %w", name, holder, ctx.Err())
+		case <-deadline:
+			return nil, &TimeoutError{Name: name, Holder: holder, Timeout: o.Timeout}
+		case <-time.After(o.PollInterval):
+		}
+	}
+}
+
+// Release gives up the lock. It fails if the lock was taken over in the meantime.
+func (l *Lock) Release() error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+
+		current, readErr := os.ReadFile(l.path)
+		if readErr != nil || !bytes.Equal(current, l.content) {
+			err = fmt.Errorf("lock %q was taken over by another process", l.name)
+			return
+		}
+		if rmErr := os.Remove(l.path); rmErr != nil {
+			err = fmt.Errorf("failed to release lock %q: %w", l.name, rmErr)
+		}
+	})
+	return err
+}
+
+// With runs fn while holding the lock called name
+func With(ctx context.Context, name string, fn func() error, opts ...Option) (err error) {
+	l, err := Acquire(ctx, name, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, l.Release())
+	}()
+	return fn()
+}
+
+// create atomically creates the lock file with its content, failing if it exists
+func (l *Lock) create() error {
+	tmp := l.path + "." + randomToken() + ".tmp"
+	if err := os.WriteFile(tmp, l.content, 0o666); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Link(tmp, l.path)
+}
+
+// inspect reads the current lock file and reports why it is stale, if it is
+func (l *Lock) inspect(staleAfter time.Duration) (holder Holder, content []byte, stale string) {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		// Released in the meantime
+		return holder, nil, ""
+	}
+	content, err = os.ReadFile(l.path)
+	if err != nil {
+		return holder, nil, ""
+	}
+	_ = json.Unmarshal(content, &holder)
+
+	if host, _ := os.Hostname(); holder.Host == host && holder.PID > 0 && !processAlive(holder.PID) {
+		return holder, content, fmt.Sprintf("process %d no longer exists", holder.PID)
+	}
+	if age := time.Since(info.ModTime()); age > staleAfter {
+		return holder, content, fmt.Sprintf("not refreshed for %s", age.Round(time.Second))
+	}
+	return holder, content, ""
+}
+
+// breakStale removes the lock file if it still holds the observed content.
+// The file is moved aside first so that a lock taken in the meantime is put back.
+// If that fails because yet another lock was created, the aside file is left
+// alone: it is still a live lock of its holder, and the caller tries again.
+func (l *Lock) breakStale(observed []byte) bool {
+	aside := l.path + "." + randomToken() + ".stale"
+	if err := os.Rename(l.path, aside); err != nil {
+		return false
+	}
+
+	if current, err := os.ReadFile(aside); err != nil || !bytes.Equal(current, observed) {
+		if err := os.Link(aside, l.path); err == nil {
+			_ = os.Remove(aside)
+		}
+		return false
+	}
+	_ = os.Remove(aside)
+	return true
+}
+
+// heartbeat refreshes the lock file's modification time until Release
+func (l *Lock) heartbeat(every time.Duration) {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				_ = os.Chtimes(l.path, now, now)
+			}
+		}
+	}()
+}
+
+// fileName maps a lock name to a readable, collision-free file name
+func fileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(safe) > 64 {
+		safe = safe[:64]
+	}
+	sum := sha256.Sum256([]byte(name))
+	return safe + "-" + hex.EncodeToString(sum[:4]) + ".lock"
+}
+
+// randomToken returns a random hex string
+func randomToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
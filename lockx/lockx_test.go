@@ -0,0 +1,164 @@
+package lockx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAcquireTakesOverStaleLock(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := []struct {
+		name   string
+		holder Holder
+		age    time.Duration
+	}{
+		{"process gone", Holder{PID: 1 << 30, Host: host}, 0},
+		{"not refreshed", Holder{PID: os.Getpid(), Host: host}, time.Hour},
+		{"other host not refreshed", Holder{PID: 1, Host: "elsewhere"}, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeLock(t, dir, "build", tt.holder, tt.age)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			l, err := Acquire(ctx, "build", Dir(dir), StaleAfter(time.Minute), Timeout(2*time.Second))
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			var holder Holder
+			readLock(t, path, &holder)
+			if holder.PID != os.Getpid() || holder.Token == "" {
+				t.Errorf("lock holder = %+v, want us", holder)
+			}
+			if err := l.Release(); err != nil {
+				t.Errorf("Release() error = %v", err)
+			}
+			assertOnlyFiles(t, dir)
+		})
+	}
+}
+
+func TestAcquireWaitsForLiveLock(t *testing.T) {
+	dir := t.TempDir()
+	held, err := Acquire(context.Background(), "deploy", Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Acquire(context.Background(), "deploy", Dir(dir), Timeout(100*time.Millisecond))
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Holder.PID != os.Getpid() {
+		t.Fatalf("Acquire() error = %v, want TimeoutError naming us", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		l, err := Acquire(context.Background(), "deploy", Dir(dir), Timeout(5*time.Second))
+		if err == nil {
+			err = l.Release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := held.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Errorf("Acquire() after Release error = %v", err)
+	}
+}
+
+func TestBreakStaleKeepsReplacedLock(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLock(t, dir, "build", Holder{PID: 1 << 30}, 0)
+	observed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process took the lock over since it was observed
+	path = writeLock(t, dir, "build", Holder{PID: os.Getpid(), Token: "new"}, 0)
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &Lock{name: "build", path: path}
+	if l.breakStale(observed) {
+		t.Fatal("breakStale() = true for a lock that changed")
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(current) {
+		t.Errorf("lock file = %q, %v, want the new holder %q", got, err, current)
+	}
+	assertOnlyFiles(t, dir, filepath.Base(path))
+
+	if !l.breakStale(current) {
+		t.Error("breakStale() = false for the observed lock")
+	}
+	assertOnlyFiles(t, dir)
+}
+
+func TestReleaseAfterTakeover(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Acquire(context.Background(), "build", Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLock(t, dir, "build", Holder{PID: os.Getpid(), Token: "other"}, 0)
+	if err := l.Release(); err == nil {
+		t.Error("Release() of a lock taken over error = nil")
+	}
+}
+
+// writeLock writes a lock file for name held by holder, last refreshed age ago
+func writeLock(t *testing.T, dir, name string, holder Holder, age time.Duration) string {
+	t.Helper()
+	holder.Name = name
+	content, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, fileName(name))
+	if err := os.WriteFile(path, content, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readLock(t *testing.T, path string, holder *Holder) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, holder); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertOnlyFiles fails unless dir holds exactly the named files
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if !slices.Equal(got, names) {
+		t.Errorf("files in lock directory = %q, want %q", got, names)
+	}
+}
@@ -0,0 +1,145 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// textHandler writes human-readable lines: the time, the level unless it is
+// INFO, the message and key=value attributes
+type textHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Leveler
+	emoji  bool
+	prefix string // Group of the attributes added later
+	attrs  []byte // Attributes added with WithAttrs, already formatted
+}
+
+// newTextHandler creates a textHandler; without emoji, messages are passed through StripEmoji
+func newTextHandler(w io.Writer, level slog.Leveler, emoji bool) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, level: level, emoji: emoji}
+}
+
+// Enabled implements slog.Handler
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b bytes.Buffer
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(time.TimeOnly) + " ")
+	}
+	if r.Level != slog.LevelInfo {
+		b.WriteString(r.Level.String() + " ")
+	}
+	msg := r.Message
+	if !h.emoji {
+		msg = StripEmoji(msg)
+	}
+	b.WriteString(msg)
+	b.Write(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.prefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(b.Bytes())
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	var b bytes.Buffer
+	b.Write(h.attrs)
+	for _, a := range attrs {
+		appendAttr(&b, h.prefix, a)
+	}
+	c.attrs = b.Bytes()
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// appendAttr writes " key=value", expanding groups into dotted keys
+func appendAttr(b *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			appendAttr(b, prefix, ga)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	var s string
+	switch v.Kind() {
+	case slog.KindDuration:
+		s = v.Duration().Round(time.Millisecond).String()
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339)
+	default:
+		s = v.String()
+	}
+	b.WriteString(" " + prefix + a.Key + "=" + quoteValue(s))
+}
+
+// quoteValue quotes s when it would not read back as a single value
+func quoteValue(s string) string {
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// plainHandler strips emoji from the messages it passes to another handler
+type plainHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler
+func (h *plainHandler) Handle(ctx context.Context, r slog.Record) error {
+	plain := slog.NewRecord(r.Time, r.Level, StripEmoji(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		plain.AddAttrs(a)
+		return true
+	})
+	return h.Handler.Handle(ctx, plain)
+}
+
+// WithAttrs implements slog.Handler
+func (h *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &plainHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h *plainHandler) WithGroup(name string) slog.Handler {
+	return &plainHandler{h.Handler.WithGroup(name)}
+}
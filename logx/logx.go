@@ -0,0 +1,179 @@
+// Package logx builds the loggers runners report progress with. A mode picks
+// the presentation: pretty human output with emoji, plain text, JSON lines,
+// or warnings and errors only. The default is set once for a whole mage run,
+// with SetDefault or the LOGX_MODE environment variable.
+package logx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ModeEnv is the environment variable selecting the default Mode, e.g. LOGX_MODE=json
+const ModeEnv = "LOGX_MODE"
+
+// Attribute keys shared by every runner
+const (
+	KeyTool       = "tool"       // Binary the runner executes, e.g. "helm"
+	KeySubcommand = "subcommand" // Operation of the tool, e.g. "upgrade"
+	KeyRelease    = "release"    // Helm release name
+	KeyDuration   = "duration"   // Wall time of the operation
+	KeyOutcome    = "outcome"    // OutcomeSuccess or OutcomeFailure
+)
+
+// Values of KeyOutcome
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Mode is how log records are presented
+type Mode int
+
+const (
+	Pretty Mode = iota // Human-readable lines with emoji
+	Plain              // Human-readable lines without emoji
+	JSON               // One JSON object per record, without emoji
+	Quiet              // Warnings and errors only, without emoji
+)
+
+// String returns the name ParseMode accepts
+func (m Mode) String() string {
+	switch m {
+	case Pretty:
+		return "pretty"
+	case Plain:
+		return "plain"
+	case JSON:
+		return "json"
+	case Quiet:
+		return "quiet"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses a mode name: pretty, plain, json or quiet
+func ParseMode(s string) (Mode, error) {
+	for _, m := range []Mode{Pretty, Plain, JSON, Quiet} {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+	return Pretty, fmt.Errorf("unknown log mode %q, want pretty, plain, json or quiet", s)
+}
+
+// New creates a logger writing to w in the given mode
+func New(w io.Writer, mode Mode) *slog.Logger {
+	switch mode {
+	case Plain:
+		return slog.New(newTextHandler(w, slog.LevelInfo, false))
+	case JSON:
+		return slog.New(&plainHandler{slog.NewJSONHandler(w, nil)})
+	case Quiet:
+		return slog.New(newTextHandler(w, slog.LevelWarn, false))
+	}
+	return slog.New(newTextHandler(w, slog.LevelInfo, true))
+}
+
+var (
+	mu         sync.Mutex
+	defaultLog *slog.Logger
+)
+
+// SetDefault makes l the logger of every runner without its own logger
+func SetDefault(l *slog.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLog = l
+}
+
+// Default returns the logger set with SetDefault. Otherwise, when ModeEnv is
+// set, it returns a logger writing to stderr in that mode, and slog.Default()
+// when it is not.
+func Default() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultLog != nil {
+		return defaultLog
+	}
+	if name := os.Getenv(ModeEnv); name != "" {
+		mode, err := ParseMode(name)
+		defaultLog = New(os.Stderr, mode)
+		if err != nil {
+			defaultLog.Warn("⚠️ Ignoring "+ModeEnv, "err", err)
+		}
+		return defaultLog
+	}
+	return slog.Default()
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying l, for the commands run with it
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or Default
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return Default()
+}
+
+// Step reports one operation of a runner: a message when it begins and one
+// with its duration and outcome when it ends, both with the same attributes
+type Step struct {
+	ctx    context.Context
+	logger *slog.Logger
+	start  time.Time
+	attrs  []any
+}
+
+// Begin logs msg with the tool, subcommand and attrs using the logger from ctx.
+// The tool and subcommand are repeated when the step ends.
+func Begin(ctx context.Context, tool, subcommand, msg string, attrs ...any) *Step {
+	s := &Step{
+		ctx:    ctx,
+		logger: FromContext(ctx),
+		start:  time.Now(),
+		attrs:  []any{KeyTool, tool, KeySubcommand, subcommand},
+	}
+	s.logger.InfoContext(ctx, msg, append(s.attrs, attrs...)...)
+	return s
+}
+
+// Done logs msg with the step's duration and a successful outcome
+func (s *Step) Done(msg string, attrs ...any) {
+	s.logger.InfoContext(s.ctx, msg, s.outcome(OutcomeSuccess, attrs)...)
+}
+
+// Fail logs that the step failed with err and returns err
+func (s *Step) Fail(err error) error {
+	s.logger.ErrorContext(s.ctx, fmt.Sprintf("❌ %s %s failed", s.attrs[1], s.attrs[3]),
+		s.outcome(OutcomeFailure, []any{"err", err})...)
+	return err
+}
+
+// outcome returns the attributes of the final message
+func (s *Step) outcome(outcome string, attrs []any) []any {
+	all := append([]any{}, s.attrs...)
+	all = append(all, attrs...)
+	return append(all, KeyDuration, time.Since(s.start), KeyOutcome, outcome)
+}
+
+// StripEmoji removes the symbols and spaces a message starts with, so
+// "📦 Installing chart..." becomes "Installing chart..."
+func StripEmoji(msg string) string {
+	return strings.TrimLeftFunc(msg, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && (r > unicode.MaxASCII || unicode.IsSpace(r))
+	})
+}
@@ -0,0 +1,155 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines returns the logged lines without their leading timestamp
+func lines(out string) []string {
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		_, rest, _ := strings.Cut(line, " ")
+		got = append(got, rest)
+	}
+	return got
+}
+
+func TestParseMode(t *testing.T) {
+	for _, m := range []Mode{Pretty, Plain, JSON, Quiet} {
+		if got, err := ParseMode(strings.ToUpper(m.String())); got != m || err != nil {
+			t.Errorf("ParseMode(%q) = %v, %v", strings.ToUpper(m.String()), got, err)
+		}
+	}
+	if _, err := ParseMode("verbose"); err == nil {
+		t.Error("ParseMode(verbose) succeeded")
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"📦 Installing chart...", "Installing chart..."},
+		{"✅ Done", "Done"},
+		{"⚠️ Ignoring", "Ignoring"},
+		{"🏋️ Heaviest command #1", "Heaviest command #1"},
+		{"3 charts", "3 charts"},
+		{"[api] ready", "[api] ready"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := StripEmoji(tt.in); got != tt.want {
+			t.Errorf("StripEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		want []string
+	}{
+		{Pretty, []string{"📦 Installing chart release=app", "WARN ⚠️ Slow release=app"}},
+		{Plain, []string{"Installing chart release=app", "WARN Slow release=app"}},
+		{Quiet, []string{"WARN Slow release=app"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		l := New(&out, tt.mode).With(KeyRelease, "app")
+		l.Info("📦 Installing chart")
+		l.Debug("🔍 Hidden")
+		l.Warn("⚠️ Slow")
+		if got := lines(out.String()); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s logged %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestNewJSON(t *testing.T) {
+	var out bytes.Buffer
+	New(&out, JSON).WithGroup("helm").Info("🚀 Upgrading", KeyRelease, "app")
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("logged %q: %v", out.String(), err)
+	}
+	if record["msg"] != "Upgrading" {
+		t.Errorf("msg = %q, want the emoji stripped", record["msg"])
+	}
+	if helm, _ := record["helm"].(map[string]any); helm[KeyRelease] != "app" {
+		t.Errorf("logged %s, want the release in the helm group", out.String())
+	}
+}
+
+func TestTextAttributes(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, Plain).With("tool", "helm").WithGroup("usage")
+	l.Info("Done",
+		"took", 1234567*time.Microsecond,
+		"cmd", "helm upgrade",
+		"set", "a=b",
+		"empty", "",
+		slog.Group("rss", "max", 2048),
+	)
+	want := `Done tool=helm usage.took=1.235s usage.cmd="helm upgrade" usage.set="a=b" usage.empty="" usage.rss.max=2048`
+	if got := lines(out.String())[0]; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestStep(t *testing.T) {
+	var out bytes.Buffer
+	ctx := NewContext(context.Background(), New(&out, Plain))
+
+	Begin(ctx, "helm", "upgrade", "🚀 Upgrading", KeyRelease, "app").Done("✅ Upgraded", KeyRelease, "app")
+	err := errors.New("timed out")
+	if got := Begin(ctx, "ko", "build", "🔨 Building").Fail(err); got != err {
+		t.Errorf("Fail() = %v, want the error passed in", got)
+	}
+
+	// Durations vary from run to run
+	got := lines(regexp.MustCompile(`duration=\S+`).ReplaceAllString(out.String(), "duration=1ms"))
+	want := []string{
+		"Upgrading tool=helm subcommand=upgrade release=app",
+		"Upgraded tool=helm subcommand=upgrade release=app duration=1ms outcome=success",
+		"Building tool=ko subcommand=build",
+		`ERROR ko build failed tool=ko subcommand=build err="timed out" duration=1ms outcome=failure`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logged\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	SetDefault(nil)
+	t.Setenv(ModeEnv, "")
+	if Default() != slog.Default() {
+		t.Error("Default() without a mode is not slog.Default()")
+	}
+
+	var out bytes.Buffer
+	custom := New(&out, Plain)
+	SetDefault(custom)
+	if FromContext(context.Background()) != custom {
+		t.Error("FromContext() without a logger is not the default")
+	}
+	carried := New(&out, JSON)
+	if FromContext(NewContext(context.Background(), carried)) != carried {
+		t.Error("FromContext() does not return the logger of the context")
+	}
+
+	SetDefault(nil)
+	t.Setenv(ModeEnv, "json")
+	if _, ok := Default().Handler().(*plainHandler); !ok {
+		t.Errorf("Default() with %s=json uses %T", ModeEnv, Default().Handler())
+	}
+}
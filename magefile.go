@@ -3,107 +3,225 @@
 package main
 
 import (
+	"context"
+	"sync"
+
 	"github.com/magefile/mage/mg"
+	"github.com/vinaycharlie01/go-mage-shared/envx"
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+	"github.com/vinaycharlie01/go-mage-shared/golang"
 	"github.com/vinaycharlie01/go-mage-shared/helmmagex"
 	"github.com/vinaycharlie01/go-mage-shared/helmx"
 	"github.com/vinaycharlie01/go-mage-shared/komagex"
 	"github.com/vinaycharlie01/go-mage-shared/kox"
+	"github.com/vinaycharlie01/go-mage-shared/toolx"
 )
 
+// loadProfile applies the environment profile named by MAGE_PROFILE to the
+// targets running commands, e.g. `MAGE_PROFILE=staging mage helm:upgrade`
+// reads .env and .env.staging. The profile is loaded by the first of them.
+var loadProfile = sync.OnceValue(func() error {
+	profile, err := envx.LoadDefault()
+	if err != nil {
+		return err
+	}
+	helmmagex.SetDefaultOptions(helmx.WithProfile(profile))
+	komagex.SetDefaultOptions(kox.WithProfile(profile))
+	golang.SetDefaultOptions(golang.WithProfile(profile))
+	return nil
+})
+
+// DryRun prints the commands of the targets that follow instead of running them,
+// e.g. `mage dryRun helm:upgrade`
+func DryRun() {
+	helmmagex.SetDefaultExecutor(execx.NewDryRun())
+	komagex.SetDefaultExecutor(execx.NewDryRun())
+	golang.SetDefaultExecutor(execx.NewDryRun())
+}
+
+// Heaviest logs the commands of the targets before it that used the most memory,
+// e.g. `mage go:test heaviest`
+func Heaviest(ctx context.Context) {
+	execx.DefaultMetrics().LogHeaviest(ctx, 5)
+}
+
+// Tools installs the tools pinned in tools.json into bin/ and updates tools.lock.json
+func Tools(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "tools")
+	defer span.Finish()
+	return span.Fail(golang.InstallToolsContext(ctx, toolx.DefaultManifest, toolx.DefaultLockfile))
+}
+
 // Helm namespace for Helm-related targets
 type Helm mg.Namespace
 
 // Install installs a Helm chart
-func (Helm) Install() error {
-	return helmmagex.Install(helmx.InstallOptions{
+func (Helm) Install(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:install")
+	defer span.Finish()
+	return span.Fail(helmmagex.InstallContext(ctx, helmx.InstallOptions{
 		ReleaseName:     "example",
 		Chart:           "./charts/example",
-		Namespace:       "default",
 		CreateNamespace: true,
 		Wait:            true,
-	})
+	}))
 }
 
 // Upgrade upgrades a Helm release
-func (Helm) Upgrade() error {
-	return helmmagex.Upgrade(helmx.UpgradeOptions{
+func (Helm) Upgrade(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:upgrade")
+	defer span.Finish()
+	return span.Fail(helmmagex.UpgradeContext(ctx, helmx.UpgradeOptions{
 		ReleaseName: "example",
 		Chart:       "./charts/example",
-		Namespace:   "default",
 		Install:     true,
 		Wait:        true,
-	})
+	}))
 }
 
 // Uninstall uninstalls a Helm release
-func (Helm) Uninstall() error {
-	return helmmagex.Uninstall("example", "default")
+func (Helm) Uninstall(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:uninstall")
+	defer span.Finish()
+	return span.Fail(helmmagex.UninstallContext(ctx, "example", ""))
 }
 
 // List lists all Helm releases
-func (Helm) List() error {
-	return helmmagex.List("", "--all-namespaces")
+func (Helm) List(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:list")
+	defer span.Finish()
+	return span.Fail(helmmagex.ListContext(ctx, "", "--all-namespaces"))
+}
+
+// Template renders a Helm chart; extra helm arguments are given as one
+// shell-quoted string, e.g. `mage helm:template "--set 'image.tag=v1 rc'"`
+func (Helm) Template(ctx context.Context, args string) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:template")
+	defer span.Finish()
+	extra, err := execx.ShellSplit(args)
+	if err != nil {
+		return span.Fail(err)
+	}
+	return span.Fail(helmmagex.TemplateContext(ctx, "example", "./charts/example", extra...))
 }
 
 // Lint lints a Helm chart
-func (Helm) Lint() error {
-	return helmmagex.Lint("./charts/example")
+func (Helm) Lint(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:lint")
+	defer span.Finish()
+	return span.Fail(helmmagex.LintContext(ctx, "./charts/example"))
 }
 
 // RepoUpdate updates Helm repositories
-func (Helm) RepoUpdate() error {
-	return helmmagex.RepoUpdate()
+func (Helm) RepoUpdate(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "helm:repoUpdate")
+	defer span.Finish()
+	return span.Fail(helmmagex.RepoUpdateContext(ctx))
 }
 
 // Ko namespace for Ko (container building) targets
 type Ko mg.Namespace
 
 // Build builds a container image with ko
-func (Ko) Build() error {
-	return komagex.Build(kox.BuildOptions{
+func (Ko) Build(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:build")
+	defer span.Finish()
+	return span.Fail(komagex.BuildContext(ctx, kox.BuildOptions{
 		ImportPath: "/Users/vinaykumar/selfhosted/enlearn/operator-1/dist/darwin_arm64/gateway-controller-linux-amd64",
 		Tags:       []string{"latest"},
 		Platform:   []string{"linux/amd64"},
 		Local:      true,
-	})
+	}))
 }
 
 // BuildMultiPlatform builds multi-platform container images
-func (Ko) BuildMultiPlatform() error {
-	return komagex.Build(kox.BuildOptions{
+func (Ko) BuildMultiPlatform(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:buildMultiPlatform")
+	defer span.Finish()
+	return span.Fail(komagex.BuildContext(ctx, kox.BuildOptions{
 		ImportPath: "./cmd/app",
 		Tags:       []string{"latest", "v1.0.0"},
 		Platform:   []string{"linux/amd64", "linux/arm64"},
 		Push:       true,
-	})
+	}))
 }
 
 // Apply builds images and applies Kubernetes manifests
-func (Ko) Apply() error {
-	return komagex.Apply(kox.ApplyOptions{
+func (Ko) Apply(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:apply")
+	defer span.Finish()
+	return span.Fail(komagex.ApplyContext(ctx, kox.ApplyOptions{
 		Filenames: []string{"k8s/deployment.yaml"},
 		Local:     false,
 		Platform:  []string{"linux/amd64"},
-	})
+	}))
 }
 
 // ApplyLocal builds images locally and applies manifests
-func (Ko) ApplyLocal() error {
-	return komagex.Apply(kox.ApplyOptions{
+func (Ko) ApplyLocal(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:applyLocal")
+	defer span.Finish()
+	return span.Fail(komagex.ApplyContext(ctx, kox.ApplyOptions{
 		Filenames: []string{"k8s/deployment.yaml"},
 		Local:     true,
 		Platform:  []string{"linux/amd64"},
-	})
+	}))
 }
 
 // Delete deletes Kubernetes resources
-func (Ko) Delete() error {
-	return komagex.Delete(kox.DeleteOptions{
+func (Ko) Delete(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:delete")
+	defer span.Finish()
+	return span.Fail(komagex.DeleteContext(ctx, kox.DeleteOptions{
 		Filenames: []string{"k8s/deployment.yaml"},
-	})
+	}))
 }
 
 // Publish publishes a container image
-func (Ko) Publish() error {
-	return komagex.Publish("./cmd/app")
+func (Ko) Publish(ctx context.Context) error {
+	if err := loadProfile(); err != nil {
+		return err
+	}
+	ctx, span := execx.StartTarget(ctx, "ko:publish")
+	defer span.Finish()
+	return span.Fail(komagex.PublishContext(ctx, "./cmd/app"))
 }
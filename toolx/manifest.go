@@ -0,0 +1,163 @@
+package toolx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Default file names of the tool manifest and its lockfile, relative to the project root
+const (
+	DefaultManifest = "tools.json"
+	DefaultLockfile = "tools.lock.json"
+)
+
+// BinDirEnv is the environment variable overriding the project-local bin directory
+const BinDirEnv = "TOOLX_BIN_DIR"
+
+// BinDir returns the absolute path of the project-local directory pinned tools
+// are installed into, "bin" in the working directory unless BinDirEnv is set
+func BinDir() string {
+	dir := os.Getenv(BinDirEnv)
+	if dir == "" {
+		dir = "bin"
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// BinPath returns where the pinned tool called name is installed
+func BinPath(name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(BinDir(), name)
+}
+
+// Platform returns the GOOS/GOARCH pair binaries are built for
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Manifest declares the tools a project pins
+type Manifest struct {
+	Tools []ManifestTool `json:"tools"`
+}
+
+// ManifestTool is a tool installed with `go install <package>@<version>`
+type ManifestTool struct {
+	Name    string `json:"name,omitempty"` // Binary name; derived from Package when empty
+	Package string `json:"package"`        // Package path of the main package
+	Version string `json:"version"`        // Module version, e.g. "v1.55.2"
+}
+
+// BinaryName returns the name of the installed binary
+func (t ManifestTool) BinaryName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	// Like go install, skip a major version suffix: example.com/cmd/tool/v2 installs "tool"
+	base := path.Base(t.Package)
+	if majorSuffix.MatchString(base) {
+		base = path.Base(path.Dir(t.Package))
+	}
+	return base
+}
+
+// majorSuffix matches a module major version path element
+var majorSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// LoadManifest reads and validates a manifest file
+func LoadManifest(file string) (*Manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse tool manifest %s: %w", file, err)
+	}
+	for i, t := range m.Tools {
+		if t.Package == "" || t.Version == "" {
+			return nil, fmt.Errorf("tool manifest %s: entry %d needs a package and a version", file, i+1)
+		}
+		if t.Version == "latest" {
+			return nil, fmt.Errorf("tool manifest %s: %s must be pinned to a version, not latest", file, t.BinaryName())
+		}
+	}
+	return &m, nil
+}
+
+// Lockfile records the tools that were installed and their checksums
+type Lockfile struct {
+	Tools []LockedTool `json:"tools"`
+}
+
+// LockedTool is an installed tool
+type LockedTool struct {
+	Name    string            `json:"name"`
+	Package string            `json:"package"`
+	Version string            `json:"version"`
+	Sum     string            `json:"sum,omitempty"`    // go.sum hash of the module, the same on every platform
+	SHA256  map[string]string `json:"sha256,omitempty"` // Binary checksum per platform, see Platform
+}
+
+// LoadLockfile reads a lockfile; a missing file yields an empty lockfile
+func LoadLockfile(file string) (*Lockfile, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Lockfile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool lockfile: %w", err)
+	}
+	var l Lockfile
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse tool lockfile %s: %w", file, err)
+	}
+	return &l, nil
+}
+
+// Lookup returns the entry for the tool called name, or nil
+func (l *Lockfile) Lookup(name string) *LockedTool {
+	for i := range l.Tools {
+		if l.Tools[i].Name == name {
+			return &l.Tools[i]
+		}
+	}
+	return nil
+}
+
+// Put adds or replaces the entry for t.Name
+func (l *Lockfile) Put(t LockedTool) {
+	if existing := l.Lookup(t.Name); existing != nil {
+		*existing = t
+		return
+	}
+	l.Tools = append(l.Tools, t)
+}
+
+// Save writes the lockfile with entries sorted by name
+func (l *Lockfile) Save(file string) error {
+	slices.SortFunc(l.Tools, func(a, b LockedTool) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool lockfile: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write tool lockfile: %w", err)
+	}
+	return nil
+}
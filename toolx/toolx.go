@@ -0,0 +1,345 @@
+// Package toolx locates the external tools runners execute and verifies
+// their versions against semantic version constraints.
+package toolx
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/vinaycharlie01/go-mage-shared/execx"
+)
+
+// Tool describes how to find out the version of a binary
+type Tool struct {
+	Name string
+	// VersionArgs are the arguments printing the version, e.g. "version --short".
+	// When empty, the version is read from the Go build info of the binary.
+	VersionArgs []string
+}
+
+// knownTools are the tools used by the runners in this module
+var knownTools = map[string]Tool{
+	"go":            {Name: "go", VersionArgs: []string{"version"}},
+	"gofmt":         {Name: "gofmt"},
+	"goimports":     {Name: "goimports"},
+	"golangci-lint": {Name: "golangci-lint", VersionArgs: []string{"--version"}},
+	"helm":          {Name: "helm", VersionArgs: []string{"version", "--short"}},
+	"ko":            {Name: "ko", VersionArgs: []string{"version"}},
+	"kubectl":       {Name: "kubectl", VersionArgs: []string{"version", "--client"}},
+}
+
+// Requirement declares a tool a runner needs and the versions it supports
+type Requirement struct {
+	Tool       string
+	Constraint string // e.g. ">=3.12"; empty accepts any version
+}
+
+// NotFoundError is returned when a required tool cannot be found
+type NotFoundError struct {
+	Tool       string
+	Paths      []string // Where the tool was looked for, in order; a bare name stands for PATH
+	Constraint string
+	Err        error // Why the last of Paths failed
+}
+
+// Error implements the error interface
+func (e *NotFoundError) Error() string {
+	var msg string
+	if len(e.Paths) == 1 && !isBareName(e.Paths[0]) {
+		switch {
+		case errors.Is(e.Err, fs.ErrNotExist):
+			msg = fmt.Sprintf("%s: %s does not exist", e.Tool, e.Paths[0])
+		case errors.Is(e.Err, fs.ErrPermission):
+			msg = fmt.Sprintf("%s: %s is not executable", e.Tool, e.Paths[0])
+		default:
+			msg = fmt.Sprintf("%s: %v", e.Tool, e.Err)
+		}
+	} else {
+		where := make([]string, 0, len(e.Paths))
+		for _, path := range e.Paths {
+			if isBareName(path) {
+				where = append(where, "in PATH")
+			} else {
+				where = append(where, "at "+path)
+			}
+		}
+		if len(where) == 0 {
+			where = append(where, "in PATH")
+		}
+		msg = fmt.Sprintf("%s not found %s", e.Tool, strings.Join(where, " or "))
+	}
+	if e.Constraint != "" {
+		msg += ", " + e.Constraint + " required"
+	}
+	return msg
+}
+
+// Unwrap returns the lookup error
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// VersionError is returned when a tool's version does not satisfy a constraint
+type VersionError struct {
+	Tool       string
+	Path       string
+	Found      Version
+	Constraint Constraint
+}
+
+// Error implements the error interface
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s %s found, %s required", e.Tool, e.Found, e.Constraint)
+}
+
+// Registry finds tools and caches their versions
+type Registry struct {
+	executor execx.Capturer
+	mu       sync.Mutex
+	tools    map[string]Tool
+	versions map[string]Version // Keyed by resolved path
+}
+
+// NewRegistry creates a Registry that runs version commands directly
+func NewRegistry() *Registry {
+	return NewRegistryWithExecutor(execx.NewExec())
+}
+
+// NewRegistryWithExecutor creates a Registry that runs version commands with executor
+func NewRegistryWithExecutor(executor execx.Capturer) *Registry {
+	return &Registry{
+		executor: executor,
+		tools:    make(map[string]Tool),
+		versions: make(map[string]Version),
+	}
+}
+
+var (
+	defaultRegistryOnce sync.Once
+	defaultRegistry     *Registry
+)
+
+// DefaultRegistry returns the process-wide Registry, so versions are looked up once per run
+func DefaultRegistry() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry()
+	})
+	return defaultRegistry
+}
+
+// Register adds or replaces how the version of a tool is determined
+func (r *Registry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[tool.Name] = tool
+}
+
+// tool returns the definition of a tool, falling back to `<name> --version`
+func (r *Registry) tool(name string) Tool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tools[name]; ok {
+		return t
+	}
+	if t, ok := knownTools[name]; ok {
+		return t
+	}
+	return Tool{Name: name, VersionArgs: []string{"--version"}}
+}
+
+// Version returns the version of the tool at path, which may also be a name
+// looked up in PATH. Versions are cached per resolved path.
+func (r *Registry) Version(ctx context.Context, name, path string) (Version, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return Version{}, &NotFoundError{Tool: name, Paths: []string{path}, Err: err}
+	}
+
+	r.mu.Lock()
+	v, ok := r.versions[resolved]
+	r.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	t := r.tool(name)
+	if len(t.VersionArgs) == 0 {
+		v, err = buildInfoVersion(resolved)
+	} else {
+		v, err = r.commandVersion(ctx, resolved, t.VersionArgs)
+	}
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to determine %s version: %w", name, err)
+	}
+
+	r.mu.Lock()
+	r.versions[resolved] = v
+	r.mu.Unlock()
+	return v, nil
+}
+
+// Check verifies that the tool at path satisfies constraint and returns its version
+func (r *Registry) Check(ctx context.Context, name, path, constraint string) (Version, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return Version{}, err
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return Version{}, &NotFoundError{Tool: name, Paths: []string{path}, Constraint: constraint, Err: err}
+	}
+	if c.IsZero() {
+		return Version{}, nil
+	}
+	v, err := r.Version(ctx, name, path)
+	if err != nil {
+		return Version{}, err
+	}
+	if !c.Check(v) {
+		return v, &VersionError{Tool: name, Path: path, Found: v, Constraint: c}
+	}
+	return v, nil
+}
+
+// commandVersion runs the tool's version command and parses its output
+func (r *Registry) commandVersion(ctx context.Context, path string, args []string) (Version, error) {
+	// The caller's stdin is not meant for a version check, which would consume it
+	ctx = execx.WithOptions(ctx, execx.Stdin(nil), execx.StdinFrom(execx.NullStdin()))
+	res, err := r.executor.Capture(ctx, path, false, args...)
+	if err != nil {
+		return Version{}, err
+	}
+	return ParseVersion(string(res.Stdout) + string(res.Stderr))
+}
+
+// buildInfoVersion reads the main module version embedded in a Go binary.
+// Binaries from the Go distribution, such as gofmt, report the Go version.
+func buildInfoVersion(path string) (Version, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return Version{}, err
+	}
+	switch info.Main.Version {
+	case "":
+		return ParseVersion(info.GoVersion)
+	case "(devel)":
+		return Version{}, errors.New("binary was built from a development version")
+	}
+	return ParseVersion(info.Main.Version)
+}
+
+// isBareName reports whether path is a name to look up in PATH rather than a path
+func isBareName(path string) bool {
+	return !strings.ContainsAny(path, `/\`)
+}
+
+// isExecutable reports whether path is an existing regular file
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Set holds the tools of one runner: the declared requirements, binaries
+// configured explicitly, and the Registry to verify them with, if any.
+type Set struct {
+	mu           sync.Mutex
+	requirements map[string]string
+	paths        map[string]string
+	registry     *Registry
+	checked      map[string]error // Keyed by tool, then the path it runs from
+}
+
+// NewSet creates a Set declaring the given requirements
+func NewSet(requirements ...Requirement) *Set {
+	s := &Set{
+		requirements: make(map[string]string),
+		paths:        make(map[string]string),
+		checked:      make(map[string]error),
+	}
+	for _, req := range requirements {
+		s.requirements[req.Tool] = req.Constraint
+	}
+	return s
+}
+
+// Requirements returns the declared requirements
+func (s *Set) Requirements() []Requirement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reqs := make([]Requirement, 0, len(s.requirements))
+	for tool, constraint := range s.requirements {
+		reqs = append(reqs, Requirement{Tool: tool, Constraint: constraint})
+	}
+	return reqs
+}
+
+// UsePath makes the set run tool from path instead of looking it up in PATH
+func (s *Set) UsePath(tool, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths[tool] = path
+}
+
+// Require replaces the version constraint of tool
+func (s *Set) Require(tool, constraint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requirements[tool] = constraint
+	for key := range s.checked {
+		if strings.HasPrefix(key, tool+"\x00") {
+			delete(s.checked, key)
+		}
+	}
+}
+
+// VerifyWith makes Command verify every tool against its constraint with r
+// before it is first used
+func (s *Set) VerifyWith(r *Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry = r
+	clear(s.checked)
+}
+
+// Command returns the name or path to execute tool with: an explicit path,
+// the pinned binary in BinDir, or the name to look up in PATH, in that order.
+// When verification is enabled, it fails if the tool is missing or its version
+// is not supported.
+func (s *Set) Command(ctx context.Context, tool string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := tool
+	pinned := BinPath(tool)
+	if p, ok := s.paths[tool]; ok {
+		path = p
+	} else if isExecutable(pinned) {
+		path = pinned
+	}
+	if s.registry == nil {
+		return path, nil
+	}
+
+	key := tool + "\x00" + path
+	if err, ok := s.checked[key]; ok {
+		return path, err
+	}
+	_, err := s.registry.Check(ctx, tool, path, s.requirements[tool])
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		// The pinned binary was looked for before PATH
+		if path == tool {
+			notFound.Paths = append([]string{pinned}, notFound.Paths...)
+		}
+		// Not remembered, the tool may be installed later on
+		return path, err
+	}
+	s.checked[key] = err
+	return path, err
+}
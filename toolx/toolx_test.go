@@ -0,0 +1,88 @@
+package toolx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestNotFoundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *NotFoundError
+		want string
+	}{
+		{"no paths", &NotFoundError{Tool: "helm"}, "helm not found in PATH"},
+		{"name", &NotFoundError{Tool: "helm", Paths: []string{"helm"}}, "helm not found in PATH"},
+		{
+			"missing path",
+			&NotFoundError{Tool: "helm", Paths: []string{"/proj/bin/helm"}, Constraint: ">=3.12", Err: os.ErrNotExist},
+			"helm: /proj/bin/helm does not exist, >=3.12 required",
+		},
+		{
+			"not executable",
+			&NotFoundError{Tool: "helm", Paths: []string{"/proj/bin/helm"}, Err: os.ErrPermission},
+			"helm: /proj/bin/helm is not executable",
+		},
+		{
+			"pinned then PATH",
+			&NotFoundError{Tool: "ko", Paths: []string{"/proj/bin/ko", "ko"}},
+			"ko not found at /proj/bin/ko or in PATH",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%s: Error() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetCommandMissingTool(t *testing.T) {
+	t.Setenv(BinDirEnv, t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+
+	s := NewSet(Requirement{Tool: "helm", Constraint: ">=3.12"})
+	s.VerifyWith(NewRegistry())
+	_, err := s.Command(context.Background(), "helm")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Command() error = %v, want NotFoundError", err)
+	}
+	if want := []string{BinPath("helm"), "helm"}; !slices.Equal(notFound.Paths, want) {
+		t.Errorf("Paths = %q, want %q", notFound.Paths, want)
+	}
+
+	explicit := filepath.Join(t.TempDir(), "helm")
+	s.UsePath("helm", explicit)
+	if _, err := s.Command(context.Background(), "helm"); err == nil || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Command() with missing explicit path error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestSetCommandFindsToolInstalledLater(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool")
+	}
+	binDir := t.TempDir()
+	t.Setenv(BinDirEnv, binDir)
+	t.Setenv("PATH", t.TempDir())
+
+	s := NewSet(Requirement{Tool: "helm", Constraint: ">=3.12"})
+	s.VerifyWith(NewRegistry())
+	if _, err := s.Command(context.Background(), "helm"); err == nil {
+		t.Fatal("Command() before installing error = nil")
+	}
+
+	script := "#!/bin/sh\necho 'version.BuildInfo{Version:\"v3.14.2\"}'\n"
+	if err := os.WriteFile(BinPath("helm"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := s.Command(context.Background(), "helm")
+	if err != nil || path != BinPath("helm") {
+		t.Errorf("Command() after installing = %q, %v, want the pinned binary", path, err)
+	}
+}
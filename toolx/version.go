@@ -0,0 +1,180 @@
+package toolx
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern finds the first semantic version in free-form output,
+// e.g. "v3.14.0+g3fc9f4b", "golangci-lint has version 1.55.2" or "go1.22.1"
+var versionPattern = regexp.MustCompile(`(?:^|[^0-9.])v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?`)
+
+// clausePattern splits a constraint clause into operator and version
+var clausePattern = regexp.MustCompile(`^(=|==|!=|>=|<=|>|<|\^|~)?v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // Pre-release identifier, e.g. "rc.1"
+}
+
+// ParseVersion extracts the first version found in s
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version found in %q", strings.TrimSpace(s))
+	}
+	v := Version{Pre: m[4]}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// String returns the version without a leading "v", e.g. "3.12.0"
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is lower, equal or higher than w.
+// A pre-release is lower than the release it precedes.
+func (v Version) Compare(w Version) int {
+	if c := cmp.Or(cmp.Compare(v.Major, w.Major), cmp.Compare(v.Minor, w.Minor), cmp.Compare(v.Patch, w.Patch)); c != 0 {
+		return c
+	}
+	switch {
+	case v.Pre == w.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case w.Pre == "":
+		return -1
+	}
+	return comparePre(v.Pre, w.Pre)
+}
+
+// comparePre compares pre-release versions by their dot-separated identifiers:
+// numerically when both are numbers, numbers first, text otherwise, and a
+// shorter list first when it is a prefix of the other, e.g. rc.9 < rc.10 < rc.10.1
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Constraint is a set of version conditions that must all hold,
+// e.g. ">=3.12", ">=1.50 <2", "^1.2" or "~0.15.1". The empty constraint accepts any version.
+type Constraint struct {
+	text    string
+	clauses []clause
+}
+
+// clause is a single comparison against a version
+type clause struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint parses space- or comma-separated conditions using the
+// operators =, !=, >, >=, <, <=, ^ (same major) and ~ (same minor)
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		m := clausePattern.FindStringSubmatch(field)
+		if m == nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: cannot parse %q", s, field)
+		}
+		op := m[1]
+		v := Version{Pre: m[5]}
+		v.Major, _ = strconv.Atoi(m[2])
+		v.Minor, _ = strconv.Atoi(m[3])
+		v.Patch, _ = strconv.Atoi(m[4])
+
+		switch op {
+		case "", "=", "==":
+			c.clauses = append(c.clauses, clause{"=", v})
+		case "!=", ">", ">=", "<", "<=":
+			c.clauses = append(c.clauses, clause{op, v})
+		case "^":
+			upper := Version{Major: v.Major + 1}
+			if v.Major == 0 {
+				upper = Version{Minor: v.Minor + 1}
+			}
+			c.clauses = append(c.clauses, clause{">=", v}, clause{"<", upper})
+		case "~":
+			c.clauses = append(c.clauses, clause{">=", v}, clause{"<", Version{Major: v.Major, Minor: v.Minor + 1}})
+		}
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics on error
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Check reports whether v satisfies every condition
+func (c Constraint) Check(v Version) bool {
+	for _, cl := range c.clauses {
+		n := v.Compare(cl.version)
+		ok := false
+		switch cl.op {
+		case "=":
+			ok = n == 0
+		case "!=":
+			ok = n != 0
+		case ">":
+			ok = n > 0
+		case ">=":
+			ok = n >= 0
+		case "<":
+			ok = n < 0
+		case "<=":
+			ok = n <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the constraint accepts any version
+func (c Constraint) IsZero() bool {
+	return len(c.clauses) == 0
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	return c.text
+}
@@ -0,0 +1,112 @@
+package toolx
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{"v3.14.0+g3fc9f4b", Version{3, 14, 0, ""}},
+		{"go version go1.22.1 linux/amd64", Version{1, 22, 1, ""}},
+		{"golangci-lint has version 1.55.2 built with go1.21.4", Version{1, 55, 2, ""}},
+		{"Client Version: v1.29.0-rc.1", Version{1, 29, 0, "rc.1"}},
+		{"ko 0.15", Version{0, 15, 0, ""}},
+		{"1.2.3", Version{1, 2, 3, ""}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "no version here", "v1"} {
+		if v, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) = %v, want error", in, v)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.1", 1},
+		{"1.0.0-rc.10", "1.0.0-rc.9", 1},
+		{"1.0.0-rc.9", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-rc.1", "1.0.0-rc.1", 0},
+	}
+	for _, tt := range tests {
+		a, b := mustVersion(t, tt.a), mustVersion(t, tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		accept     []string
+		reject     []string
+	}{
+		{"", []string{"0.0.1", "99.0.0"}, nil},
+		{">=3.12", []string{"3.12.0", "3.14.2", "4.0.0"}, []string{"3.11.9", "3.12.0-rc.1"}},
+		{">=1.50 <2", []string{"1.50.0", "1.99.0"}, []string{"1.49.0", "2.0.0"}},
+		{">=1.50,<2", []string{"1.55.2"}, []string{"2.1.0"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.15", []string{"0.15.0", "0.15.9"}, []string{"0.16.0", "0.14.0"}},
+		{"~0.15.1", []string{"0.15.1", "0.15.9"}, []string{"0.15.0", "0.16.0"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"v1.2.3", []string{"1.2.3"}, []string{"1.2.2"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{">1.2 <=1.4", []string{"1.2.1", "1.4.0"}, []string{"1.2.0", "1.4.1"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) error = %v", tt.constraint, err)
+			continue
+		}
+		for _, v := range tt.accept {
+			if !c.Check(mustVersion(t, v)) {
+				t.Errorf("%q rejects %s", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.reject {
+			if c.Check(mustVersion(t, v)) {
+				t.Errorf("%q accepts %s", tt.constraint, v)
+			}
+		}
+	}
+
+	for _, in := range []string{">=", "latest", ">=1.x", "=>1.2"} {
+		if _, err := ParseConstraint(in); err == nil {
+			t.Errorf("ParseConstraint(%q) error = nil, want error", in)
+		}
+	}
+}
+
+func mustVersion(t *testing.T, s string) Version {
+	t.Helper()
+	v, err := ParseVersion(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}